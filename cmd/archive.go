@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/utils"
+)
+
+// archiveEntry is one line of the download archive, a JSON object per
+// subtitle file written.
+type archiveEntry struct {
+	SubtitleID string    `json:"subtitle_id"`
+	EpisodeID  string    `json:"episode_id,omitempty"`
+	SeasonID   string    `json:"season_id,omitempty"`
+	Lang       string    `json:"lang,omitempty"`
+	IsMachine  bool      `json:"is_machine,omitempty"`
+	Hash       string    `json:"hash,omitempty"`
+	SourceHash string    `json:"source_hash,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// downloadArchive is the --download-archive file, loaded once and appended
+// to as subtitles are written. It is safe for concurrent use.
+type downloadArchive struct {
+	mu      sync.Mutex
+	name    string
+	entries map[string]archiveEntry
+}
+
+// archive is the archive of the running dl command, nil without
+// --download-archive.
+var archive *downloadArchive
+
+// openArchive loads the archive in name. An archive in the old format, one
+// subtitle ID per line, is converted in place and the old file is kept as
+// name.bak. The imported entries get the modification time of the old file.
+func openArchive(name string) (*downloadArchive, error) {
+	a := &downloadArchive{name: name, entries: make(map[string]archiveEntry)}
+
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		legacy bool
+		order  []string
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "{"):
+			var e archiveEntry
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				return nil, fmt.Errorf("[archive: %s, line %d] %w", name, n, err)
+			}
+			if _, ok := a.entries[e.SubtitleID]; !ok {
+				order = append(order, e.SubtitleID)
+			}
+			a.entries[e.SubtitleID] = e
+		default:
+			legacy = true
+			if _, ok := a.entries[line]; !ok {
+				order = append(order, line)
+				a.entries[line] = archiveEntry{SubtitleID: line, Time: info.ModTime()}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if legacy {
+		if err := a.migrate(data, order); err != nil {
+			return nil, fmt.Errorf("[archive: %s] %w", name, err)
+		}
+	}
+	return a, nil
+}
+
+// migrate rewrites the archive with the entries of ids as JSON, keeping the
+// old content as a backup.
+func (a *downloadArchive) migrate(old []byte, ids []string) error {
+	if err := os.WriteFile(a.name+".bak", old, 0o600); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, id := range ids {
+		if err := enc.Encode(a.entries[id]); err != nil {
+			return err
+		}
+	}
+	return utils.WriteFile(a.name, b.Bytes(), time.Now())
+}
+
+// has reports whether the subtitle with id is recorded.
+func (a *downloadArchive) has(id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.entries[id]
+	return ok
+}
+
+// get returns the entry of the subtitle with id, the zero entry when it is
+// not recorded.
+func (a *downloadArchive) get(id string) archiveEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.entries[id]
+}
+
+// add records e unless its subtitle is recorded already. With overwrite set
+// the entry is recorded again, the later line wins when the archive is
+// loaded.
+func (a *downloadArchive) add(e archiveEntry, overwrite bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.entries[e.SubtitleID]; ok && !overwrite {
+		return nil
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(a.name, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	a.entries[e.SubtitleID] = e
+	return nil
+}
+
+// contentHash returns the hash recorded for a subtitle file.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
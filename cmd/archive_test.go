@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveMigrate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "archive.txt")
+	legacy := "1011\n1021\n\n1011\n"
+	if err := os.WriteFile(name, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := openArchive(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.has("1011") || !a.has("1021") || a.has("1031") {
+		t.Errorf("entries = %v, want 1011 and 1021", a.entries)
+	}
+
+	if bak, err := os.ReadFile(name + ".bak"); err != nil || string(bak) != legacy {
+		t.Errorf("backup = %q, %v, want %q", bak, err, legacy)
+	}
+
+	if err := a.add(archiveEntry{SubtitleID: "1031", EpisodeID: "103", Lang: "en", Hash: contentHash([]byte("x"))}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err = openArchive(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.entries) != 3 || a.entries["1031"].EpisodeID != "103" || a.entries["1011"].Time.IsZero() {
+		t.Errorf("entries after reload = %+v", a.entries)
+	}
+	if _, err := os.Stat(name + ".bak"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// batchItem is an ID or URL to download, from the command line or a line of
+// --batch-file, with the options that override the command line for it.
+type batchItem struct {
+	arg  string
+	line int
+
+	languages []string
+	sections  []string
+	episodes  []string
+}
+
+// label names it in error messages.
+func (it batchItem) label() string {
+	if it.line > 0 {
+		return fmt.Sprintf("[batch file: %s, line %d, ID: %s]", batchFile, it.line, it.arg)
+	}
+	return fmt.Sprintf("[ID: %s]", it.arg)
+}
+
+func (it batchItem) hasOverrides() bool {
+	return it.languages != nil || it.sections != nil || it.episodes != nil
+}
+
+// apply sets the options of it for the next download and returns a function
+// restoring the command line options. A language list replaces
+// --language-fallback and --dual as well.
+func (it batchItem) apply() (restore func()) {
+	saved := [...][]string{languages, languageFallback, dualLanguages, sectionSelect, episodeSelect}
+	if it.languages != nil {
+		languages, languageFallback, dualLanguages = it.languages, nil, nil
+	}
+	if it.sections != nil {
+		sectionSelect = it.sections
+	}
+	if it.episodes != nil {
+		episodeSelect = it.episodes
+	}
+
+	return func() {
+		languages, languageFallback, dualLanguages, sectionSelect, episodeSelect = saved[0], saved[1], saved[2], saved[3], saved[4]
+	}
+}
+
+// itemErrors collects the errors of the IDs and URLs that failed in a dl
+// run.
+type itemErrors []error
+
+func (e itemErrors) Error() string {
+	msg := make([]string, 0, len(e)+1)
+	msg = append(msg, fmt.Sprintf("%d ID(s) failed:", len(e)))
+	for _, err := range e {
+		msg = append(msg, "  "+strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+	return strings.Join(msg, "\n")
+}
+
+// Is reports whether one of the errors matches target.
+func (e itemErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// runItems downloads items one after another. An item that fails does not
+// stop the ones after it, the errors are returned together at the end. Only
+// a signal or --timeout ends the run early.
+func runItems(ctx context.Context, items []batchItem) error {
+	var failed itemErrors
+	for _, it := range items {
+		if err := stopped(ctx); err != nil {
+			failed = append(failed, err)
+			break
+		}
+
+		restore := it.apply()
+		ref, err := client.ResolveRef(ctx, it.arg)
+		if err == nil {
+			err = runDl(ctx, ref)
+		}
+		restore()
+
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s %w", it.label(), err))
+			if stopped(ctx) != nil {
+				break
+			}
+		}
+	}
+
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return failed[0]
+	}
+	return failed
+}
+
+// readBatch reads the items of a batch file, or of stdin when name is "-".
+// Every line holds an ID or URL, optionally followed by --language,
+// --section-range and --episode-range. Empty lines and everything after a
+// # starting a word are ignored.
+func readBatch(name string) ([]batchItem, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var items []batchItem
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		it, ok, err := parseBatchLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("[batch file: %s, line %d] %w", name, n, err)
+		}
+		if ok {
+			it.line = n
+			items = append(items, it)
+		}
+	}
+	return items, scanner.Err()
+}
+
+// parseBatchLine parses one line of a batch file. ok is false for lines
+// without an item.
+func parseBatchLine(s string) (it batchItem, ok bool, err error) {
+	var words []string
+	for _, w := range strings.Fields(s) {
+		if strings.HasPrefix(w, "#") {
+			break
+		}
+		words = append(words, w)
+	}
+	if len(words) == 0 {
+		return it, false, nil
+	}
+
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringSliceVarP(&it.languages, "language", "l", nil, "")
+	fs.StringArrayVar(&it.sections, "section-range", nil, "")
+	fs.StringArrayVar(&it.episodes, "episode-range", nil, "")
+	if err := fs.Parse(words); err != nil {
+		return it, false, err
+	}
+
+	if fs.NArg() != 1 {
+		return it, false, fmt.Errorf("expected one ID or URL, got %d", fs.NArg())
+	}
+	it.arg = fs.Arg(0)
+	return it, true, nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// useTestAPI points client at a test server running handler for the rest of
+// the test.
+func useTestAPI(t *testing.T, handler http.HandlerFunc) {
+	ts := httptest.NewServer(handler)
+	baseURL := client.BaseURL
+	client.BaseURL = ts.URL
+	t.Cleanup(func() {
+		client.BaseURL = baseURL
+		ts.Close()
+	})
+}
+
+func TestParseBatchLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want batchItem
+		ok   bool
+	}{
+		{line: "1049041", want: batchItem{arg: "1049041"}, ok: true},
+		{line: "  # a comment", ok: false},
+		{line: "", ok: false},
+		{
+			line: "https://www.bilibili.tv/en/play/1049041 -l en,th --section-range 1 --episode-range 2-4 # season 1",
+			want: batchItem{
+				arg:       "https://www.bilibili.tv/en/play/1049041",
+				languages: []string{"en", "th"},
+				sections:  []string{"1"},
+				episodes:  []string{"2-4"},
+			},
+			ok: true,
+		},
+		{line: "--language id 37738", want: batchItem{arg: "37738", languages: []string{"id"}}, ok: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseBatchLine(tt.line)
+		if err != nil {
+			t.Errorf("parseBatchLine(%q) error = %v", tt.line, err)
+			continue
+		}
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseBatchLine(%q) = %+v, %t, want %+v, %t", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	for _, line := range []string{"1 2", "1 --bogus", "-l en"} {
+		if _, _, err := parseBatchLine(line); err == nil {
+			t.Errorf("parseBatchLine(%q) succeeded, want error", line)
+		}
+	}
+}
+
+func TestRunItemsContinues(t *testing.T) {
+	defer func(f string) { batchFile = f }(batchFile)
+	batchFile = "seasons.txt"
+
+	var seasons []string
+	useTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		seasons = append(seasons, r.URL.Query().Get("season_id"))
+		fmt.Fprint(w, `{"code":-404,"message":"not found"}`)
+	})
+
+	items := []batchItem{{arg: "https://example.com/1", line: 1}, {arg: "37738", line: 3}}
+	err := runItems(context.Background(), items)
+
+	var failed itemErrors
+	if !errors.As(err, &failed) || len(failed) != 2 {
+		t.Fatalf("runItems() error = %v, want 2 errors", err)
+	}
+	if !reflect.DeepEqual(seasons, []string{"37738"}) {
+		t.Errorf("requested seasons %v, want [37738]", seasons)
+	}
+	for i, line := range []string{"line 1,", "line 3,"} {
+		if !strings.Contains(failed[i].Error(), line) {
+			t.Errorf("error %d = %q, want it to name %s", i, failed[i], line)
+		}
+	}
+}
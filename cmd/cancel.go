@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fatih/color"
+)
+
+// errInterrupted is returned by dl when SIGINT or SIGTERM stopped it before
+// the end.
+var errInterrupted = errors.New("interrupted")
+
+// interrupted is closed on the first SIGINT or SIGTERM during dl. No new
+// episode is started after that, the ones in progress finish so their files
+// and archive entries are complete. A nil channel is never closed.
+var interrupted <-chan struct{}
+
+// notifyInterrupt sets up interrupted. A second signal kills the process as
+// usual. The returned function stops listening for signals.
+func notifyInterrupt() (stop func()) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+	ch := make(chan struct{})
+	interrupted = ch
+	quit := make(chan struct{})
+	go func() {
+		select {
+		case <-sig:
+			signal.Stop(sig)
+			fmt.Fprintln(color.Output, color.YellowString("Interrupted, finishing the episodes in progress (press Ctrl-C again to quit)"))
+			close(ch)
+		case <-quit:
+		}
+	}()
+
+	return func() {
+		signal.Stop(sig)
+		close(quit)
+	}
+}
+
+// stopped returns why dl has to stop starting episodes: errInterrupted after
+// a signal, the error of ctx once --timeout expired, nil otherwise.
+func stopped(ctx context.Context) error {
+	select {
+	case <-interrupted:
+		return errInterrupted
+	default:
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("--timeout of %s reached: %w", timeout, err)
+	}
+	return nil
+}
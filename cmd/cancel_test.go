@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunJobsStopped(t *testing.T) {
+	defer func(c *checkpoint, ch <-chan struct{}) { ckpt, interrupted = c, ch }(ckpt, interrupted)
+	ckpt = nil
+	jobs := []dlJob{{episodeID: "101", filename: "Show/E1"}, {episodeID: "102", filename: "Show/E2"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := runJobs(ctx, jobs); !errors.Is(err, context.Canceled) {
+		t.Errorf("runJobs() with a canceled context = %v, want %v", err, context.Canceled)
+	}
+
+	ch := make(chan struct{})
+	close(ch)
+	interrupted = ch
+	if err := runJobs(context.Background(), jobs); !errors.Is(err, errInterrupted) {
+		t.Errorf("runJobs() after a signal = %v, want %v", err, errInterrupted)
+	}
+}
@@ -1,9 +1,10 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
 	"github.com/K0ng2/bilisubdl/utils"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
@@ -20,44 +22,167 @@ import (
 )
 
 var (
-	language      string
-	output        string
-	listLang      bool
-	listSection   bool
-	listEpisode   bool
-	overwrite     bool
-	dlepisode     bool
-	isJson        bool
-	quiet         bool
-	fastCheck     bool
-	skipMachine   bool
-	dlArchive     string
-	epFilename    string
-	sectionSelect []string
-	episodeSelect []string
+	languages        []string
+	languageFallback []string
+	preferHuman      bool
+	dualLanguages    []string
+	shiftBy          time.Duration
+	fpsFrom          float64
+	fpsTo            float64
+	convertTo        string
+	format           string
+	assStyle         = subtitle.DefaultStyle
+	output           string
+	listLang         bool
+	listSection      bool
+	listEpisode      bool
+	overwrite        bool
+	update           bool
+	resume           bool
+	dlepisode        bool
+	isJson           bool
+	quiet            bool
+	fastCheck        bool
+	keepRaw          bool
+	skipMachine      bool
+	dlArchive        string
+	epFilename       string
+	batchFile        string
+	outTemplate      string
+	layout           string
+	matchDir         string
+	concurrency      int
+	retries          int
+	retryWait        time.Duration
+	timeout          time.Duration
+	rateLimit        float64
+	rateBurst        int
+	sectionSelect    []string
+	episodeSelect    []string
+)
+
+// allLanguages selects every subtitle language of an episode.
+const allLanguages = "all"
+
+// formats lists the values accepted by --format.
+var formats = []string{"srt", "ass", "vtt"}
+
+var (
+	client  = bilibili.NewClient()
+	outTmpl *outputTemplate
+	// cancelTimeout releases the context of --timeout.
+	cancelTimeout context.CancelFunc = func() {}
 )
 
 var RootCmd = &cobra.Command{
 	Use: "bilisubdl",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		client.Retries = retries
+		client.RetryWait = retryWait
+		client.Limiter = utils.NewRateLimiter(rateLimit, rateBurst)
+		if timeout > 0 {
+			var ctx context.Context
+			ctx, cancelTimeout = context.WithTimeout(cmd.Context(), timeout)
+			cmd.SetContext(ctx)
+		}
+	},
 }
 
 var dlCmd = &cobra.Command{
-	Use:     "dl [ID] [flags]",
+	Use:     "dl [ID|URL]... [flags]",
 	Short:   "command downloads the subtitle for the given anime ID.",
-	Args:    cobra.MinimumNArgs(1),
-	Example: "bilisubdl dl 37738 1042594 -l th -o /path/to/output",
+	Example: "bilisubdl dl 37738 1042594 -l th -o /path/to/output\nbilisubdl dl https://www.bilibili.tv/en/play/1049041/2075361 -l en\nbilisubdl dl --batch-file seasons.txt -l en",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if dlepisode {
-			return runDlEpisode(args)
-		} else {
-			for _, s := range args {
-				err := runDl(s)
-				if err != nil {
-					return fmt.Errorf("[ID: %s] %w", s, err)
+		if len(args) == 0 && batchFile == "" {
+			return fmt.Errorf("requires at least 1 ID or URL, or --batch-file")
+		}
+		items := make([]batchItem, len(args))
+		for i, s := range args {
+			items[i] = batchItem{arg: s}
+		}
+		if batchFile != "" {
+			batch, err := readBatch(batchFile)
+			if err != nil {
+				return err
+			}
+			items = append(items, batch...)
+		}
+
+		hasLanguage := len(languages) > 0 || len(languageFallback) > 0 || len(dualLanguages) > 0
+		for _, it := range items {
+			if !hasLanguage && it.languages == nil {
+				return fmt.Errorf("one of the flags --language, --language-fallback or --dual is required")
+			}
+			if dlepisode && it.hasOverrides() {
+				return fmt.Errorf("[batch file: %s, line %d] options per line do not work with --dlepisode", batchFile, it.line)
+			}
+		}
+		if dualLanguages != nil && len(dualLanguages) != 2 {
+			return fmt.Errorf("--dual takes exactly two languages (e.g., zh-Hans,en)")
+		}
+		if !slices.Contains(formats, format) {
+			return fmt.Errorf("invalid format %q, must be one of %s", format, strings.Join(formats, ", "))
+		}
+		if matchDir != "" {
+			if dlepisode {
+				return fmt.Errorf("--match-dir needs an anime ID and does not work with --dlepisode")
+			}
+			output = matchDir
+		}
+		tmpl, err := layoutTemplate()
+		if err != nil {
+			return err
+		}
+		outTmpl = nil
+		if tmpl != "" {
+			if outTmpl, err = parseTemplate(tmpl); err != nil {
+				return err
+			}
+			for _, it := range append(items, batchItem{languages: languages}) {
+				manyLangs := len(it.languages) > 1 || slices.Contains(it.languages, allLanguages) || len(dualLanguages) > 0 && keepRaw
+				if manyLangs && !outTmpl.has("lang", "lang_title") {
+					return fmt.Errorf("--output-template needs {lang} when more than one language is downloaded")
 				}
 			}
 		}
+		archive = nil
+		if dlArchive != "" {
+			if archive, err = openArchive(dlArchive); err != nil {
+				return err
+			}
+		}
+		if ckpt, err = openCheckpoint(output, resumeArgs(), resume); err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		summary.reset()
+		defer notifyInterrupt()()
+
+		ctx := cmd.Context()
+		if dlepisode {
+			var ids []string
+			if ids, err = episodeIDs(ctx, items); err == nil {
+				err = runDlEpisode(ctx, ids)
+			}
+		} else {
+			err = runItems(ctx, items)
+		}
+
+		if err == nil {
+			err = ckpt.remove()
+		}
+
+		if !quiet {
+			summary.render()
+		}
+
+		if err != nil {
+			return err
+		}
 
+		if n := summary.count(statusMissing); n > 0 {
+			return fmt.Errorf("%w for %d requested subtitle(s)", errMissing, n)
+		}
 		return nil
 	},
 }
@@ -77,7 +202,7 @@ var searchCmd = &cobra.Command{
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		err := runSearch(args[0])
+		err := runSearch(cmd.Context(), args[0])
 		if err != nil {
 			return fmt.Errorf("[keyword: %s] %w", args[0], err)
 		}
@@ -98,16 +223,16 @@ If no day is provided, the current day of the week is used.
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
-			return runTimeline("")
+			return runTimeline(cmd.Context(), "")
 		}
 
-		return runTimeline(args[0])
+		return runTimeline(cmd.Context(), args[0])
 	},
 	Example: "bilisubdl timeline\nbilisubdl timeline wed --json",
 }
 
 var listCmd = &cobra.Command{
-	Use:   "list [ID] [flags]",
+	Use:   "list [ID|URL] [flags]",
 	Short: "command allows you to view information about available subtitles and episodes for a given anime ID",
 	Args: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
@@ -121,30 +246,82 @@ var listCmd = &cobra.Command{
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runList(args[0])
+		ref, err := client.ResolveRef(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("[ID: %s] %w", args[0], err)
+		}
+		return runList(cmd.Context(), ref.SeasonID)
 	},
 }
 
 func init() {
-	RootCmd.AddCommand(dlCmd, searchCmd, timelineCmd, listCmd)
+	RootCmd.AddCommand(dlCmd, searchCmd, timelineCmd, listCmd, shiftCmd, convertCmd)
+	rootFlag := RootCmd.PersistentFlags()
+	rootFlag.IntVar(&retries, "retries", bilibili.DefaultRetries, "sets how many times a request is retried after a transient error (e.g., HTTP 429 or 5xx).")
+	rootFlag.DurationVar(&retryWait, "retry-wait", bilibili.DefaultRetryWait, "sets the initial wait between retries, doubled after every attempt unless the server sends Retry-After.")
+	rootFlag.Float64Var(&rateLimit, "rate-limit", 0, "limits the number of requests per second sent to bilibili, shared by all downloads (0 means no limit).")
+	rootFlag.IntVar(&rateBurst, "rate-burst", 1, "sets how many requests may be sent at once before --rate-limit applies.")
+	rootFlag.DurationVar(&timeout, "timeout", 0, "stops the whole command after a duration (e.g., `30m`), on top of the 30s limit of every request (0 means no limit).")
 	selectFlags := flag.NewFlagSet("selectFlags", flag.ExitOnError)
 	selectFlags.StringArrayVar(&sectionSelect, "section-range", nil, "selects a range of episodes to download subtitles for (e.g., `5`, `8-10`).")
 	selectFlags.StringArrayVar(&episodeSelect, "episode-range", nil, "selects a range of sections to download subtitles for (e.g., `5`, `8-10`).")
 
+	timingFlags := flag.NewFlagSet("timingFlags", flag.ExitOnError)
+	timingFlags.DurationVar(&shiftBy, "shift", 0, "moves every subtitle line by a duration (e.g., `+1.5s`, `-500ms`).")
+	timingFlags.Float64Var(&fpsFrom, "fps-from", 0, "sets the framerate the subtitle is timed for (e.g., 25). Use with --fps-to.")
+	timingFlags.Float64Var(&fpsTo, "fps-to", 0, "sets the framerate of the video to time the subtitle for (e.g., 23.976). Use with --fps-from.")
+
+	styleFlags := flag.NewFlagSet("styleFlags", flag.ExitOnError)
+	styleFlags.StringVar(&assStyle.Font, "ass-font", assStyle.Font, "sets the font of the default style in ASS output.")
+	styleFlags.IntVar(&assStyle.Size, "ass-font-size", assStyle.Size, "sets the font size of the default style in ASS output (1080p canvas).")
+	styleFlags.Float64Var(&assStyle.Outline, "ass-outline", assStyle.Outline, "sets the outline width of the default style in ASS output.")
+	styleFlags.IntVar(&assStyle.MarginL, "ass-margin-l", assStyle.MarginL, "sets the left margin of the default style in ASS output.")
+	styleFlags.IntVar(&assStyle.MarginR, "ass-margin-r", assStyle.MarginR, "sets the right margin of the default style in ASS output.")
+	styleFlags.IntVar(&assStyle.MarginV, "ass-margin-v", assStyle.MarginV, "sets the vertical margin of the default style in ASS output.")
+
 	dlFlag := dlCmd.PersistentFlags()
-	dlFlag.StringVarP(&language, "language", "l", "", "sets the subtitle language to download (e.g., `en` for English, `zh` for Chinese).")
+	dlFlag.StringSliceVarP(&languages, "language", "l", nil, "sets the subtitle languages to download, separated by commas (e.g., `en`, `en,th,id`, or `all` for every language).")
+	dlFlag.StringSliceVar(&languageFallback, "language-fallback", nil, "downloads the first available language of a comma separated list (e.g., `en,zh-Hans,zh-Hant`).")
+	dlFlag.StringSliceVar(&dualLanguages, "dual", nil, "merges two languages into one bilingual subtitle, the first one on the bottom (e.g., `zh-Hans,en`).")
+	dlFlag.BoolVar(&preferHuman, "prefer-human", false, "prefers a human translation anywhere in --language-fallback over a machine translation earlier in it.")
 	dlFlag.StringVarP(&output, "output", "o", "./", "sets the output directory where the downloaded subtitle file will be saved (default is the current directory).")
+	dlFlag.StringVarP(&format, "format", "f", "srt", "sets the subtitle format (srt, ass or vtt). JSON and ASS subtitles from the API are converted, ASS is kept as is when the format is ass.")
+	dlFlag.AddFlagSet(styleFlags)
 	dlFlag.BoolVar(&dlepisode, "dlepisode", false, "downloads the subtitle for the specified episode ID.")
+	dlFlag.StringVar(&batchFile, "batch-file", "", "reads IDs or URLs from a `FILE` (- for stdin), one per line, each optionally followed by --language, --section-range and --episode-range for that line. Lines starting with # are comments.")
 	dlFlag.StringVar(&epFilename, "filename", "", "sets the subtitle filename using a specified format. This option only works in combination with `--dlepisode` flag. (e.g. Abc %d = Abc 1, Abc %02d = Abc 02)")
+	dlFlag.StringVar(&outTemplate, "output-template", "", "sets the subtitle path inside the output directory, without extension, from a `TEMPLATE` of episode fields (e.g., {season_title}/S{section_index:02d}E{episode_number:02d}.{lang}). See README for the fields.")
+	dlFlag.StringVar(&layout, "layout", layoutDefault, "names the subtitles after a preset: `default` or mediaserver (Show/Season 01/Show - S01E05.en.srt for Plex, Jellyfin and Kodi).")
+	dlFlag.StringVar(&matchDir, "match-dir", "", "pairs the episodes with the video files in a `DIR` by the episode number in their names (e.g., S01E05, - 05, [05]) and saves every subtitle next to its video with the same name.")
 	dlFlag.BoolVarP(&overwrite, "overwrite", "w", false, "forces the tool to overwrite existing subtitle files in the output directory.")
+	dlFlag.BoolVar(&update, "update", false, "downloads existing and archived subtitles again and replaces the ones that changed upstream, keeping the old file as .prev.")
+	dlFlag.BoolVar(&resume, "resume", false, "skips the episodes finished by an earlier run of the same command that stopped before the end.")
 	dlFlag.BoolVarP(&quiet, "quiet", "q", false, "suppresses verbose output.")
 	dlFlag.BoolVar(&skipMachine, "skip-machine", false, "skips Machine translation.")
 	dlFlag.AddFlagSet(selectFlags)
+	dlFlag.AddFlagSet(timingFlags)
 	dlFlag.BoolVar(&fastCheck, "fast-check", false, "skips checking the subtitle extension from API.")
-	dlFlag.StringVar(&dlArchive, "download-archive", "", "Create a FILE to keep track of all downloaded and skipped subtitles, and use it to prevent downloading any files that are already recorded in it. Additionally, record the IDs of all newly downloaded subtitles in the same FILE.")
-	dlCmd.MarkPersistentFlagRequired("language")
+	dlFlag.BoolVar(&keepRaw, "keep-raw", false, "keeps the subtitle as returned by the API and the track it came from in a .raw folder next to the converted file.")
+	dlFlag.IntVar(&concurrency, "concurrency", 1, "sets the number of episodes to download at the same time.")
+	dlFlag.StringVar(&dlArchive, "download-archive", "", "Create a FILE to keep track of all downloaded and skipped subtitles, and use it to prevent downloading any files that are already recorded in it. Additionally, record every newly downloaded subtitle in the same FILE, one JSON object per line. Archives with one ID per line are converted.")
+	dlCmd.MarkFlagsMutuallyExclusive("language", "language-fallback", "dual")
 	dlCmd.MarkFlagsRequiredTogether("filename", "dlepisode")
-	dlCmd.MarkFlagsMutuallyExclusive("fast-check", "overwrite")
+	dlCmd.MarkFlagsMutuallyExclusive("filename", "output-template", "layout", "match-dir")
+	dlCmd.MarkFlagsMutuallyExclusive("output", "match-dir")
+	dlCmd.MarkFlagsMutuallyExclusive("fast-check", "overwrite", "update")
+	dlCmd.MarkFlagsRequiredTogether("fps-from", "fps-to")
+
+	shiftFlag := shiftCmd.PersistentFlags()
+	shiftFlag.AddFlagSet(timingFlags)
+	shiftCmd.MarkFlagsRequiredTogether("fps-from", "fps-to")
+
+	convertFlag := convertCmd.PersistentFlags()
+	convertFlag.StringVarP(&convertTo, "to", "t", "", "sets the format to convert to (srt, vtt, ass or txt).")
+	convertFlag.BoolVarP(&overwrite, "overwrite", "w", false, "overwrites existing files.")
+	convertFlag.AddFlagSet(styleFlags)
+	convertFlag.AddFlagSet(timingFlags)
+	convertCmd.MarkPersistentFlagRequired("to")
+	convertCmd.MarkFlagsRequiredTogether("fps-from", "fps-to")
 
 	shareFlags := flag.NewFlagSet("shareFlags", flag.ExitOnError)
 	shareFlags.BoolVar(&isJson, "json", false, "displays the output in JSON format.")
@@ -163,49 +340,125 @@ func init() {
 	listCmd.MarkFlagsMutuallyExclusive("language", "section-range", "episode-range")
 }
 
-func runDl(id string) error {
+// runDl downloads the subtitles of a season, or of one of its episodes when
+// ref points to an episode.
+func runDl(ctx context.Context, ref bilibili.Ref) error {
 	var (
+		id              = ref.SeasonID
 		title, filename string
 		maxEp           int
+		jobs            []dlJob
 	)
 
-	query := map[string]string{
-		"s_locale":  "en_US",
-		"season_id": id,
-	}
-
-	info, err := bilibili.GetApi(new(bilibili.Info), bilibili.BilibiliSeasonInfoAPI, query)
+	info, err := client.SeasonInfo(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	epList, err := bilibili.GetApi(new(bilibili.Episodes), bilibili.BilibiliEpisodeInfoAPI, query)
+	epList, err := client.Episodes(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	// The ranges pick episodes of a season, an episode link names its
+	// episode already.
+	sectionSel, episodeSel := sectionSelect, episodeSelect
+	if ref.IsEpisode() {
+		sectionSel, episodeSel = nil, nil
+	}
+
 	title = utils.CleanText(info.Data.Season.Title)
-	sectionIndex := utils.ListSelect(sectionSelect, len(epList.Data.Sections))
+	seasons, offsets := seasonNumbers(epList.Data.Sections)
+	absolute := 0
+	sectionIndex := utils.ListSelect(sectionSel, len(epList.Data.Sections))
 	for ji, j := range epList.Data.Sections {
-		if sectionSelect == nil || slices.Contains(sectionIndex, ji+1) {
-			episodeIndex := utils.ListSelect(episodeSelect, maxEp+len(j.Episodes))
+		first := absolute
+		if seasons[ji] > 0 {
+			absolute += len(j.Episodes)
+		}
+		if sectionSel == nil || slices.Contains(sectionIndex, ji+1) {
+			episodeIndex := utils.ListSelect(episodeSel, maxEp+len(j.Episodes))
 			for si, s := range j.Episodes {
-				if episodeSelect == nil || slices.Contains(episodeIndex, maxEp+si+1) {
-					filename = filepath.Join(title, fmt.Sprintf("%s.%s", utils.CleanText(s.TitleDisplay), language))
-
-					if err := downloadSub(s.EpisodeID.String(), filename, s.PublishTime); err != nil {
-						return err
-					}
+				if episodeSel == nil || slices.Contains(episodeIndex, maxEp+si+1) {
+					filename = filepath.Join(title, utils.CleanText(s.TitleDisplay))
+					jobs = append(jobs, dlJob{
+						episodeID:   s.EpisodeID.String(),
+						filename:    filename,
+						publishTime: s.PublishTime,
+						fields: nameFields{
+							SeasonTitle:    info.Data.Season.Title,
+							SeasonID:       id,
+							SectionTitle:   j.Title,
+							SectionIndex:   ji + 1,
+							EpisodeNumber:  si + 1,
+							SeasonNumber:   seasons[ji],
+							SeasonEpisode:  offsets[ji] + si + 1,
+							AbsoluteNumber: first + si + 1,
+							EpisodeID:      s.EpisodeID.String(),
+							ShortTitle:     s.ShortTitleDisplay,
+							LongTitle:      s.LongTitleDisplay,
+							PublishTime:    s.PublishTime,
+						},
+					})
 				}
 			}
 			maxEp += len(j.Episodes)
 		}
 	}
-	return nil
+
+	if ref.IsEpisode() {
+		i := slices.IndexFunc(jobs, func(j dlJob) bool { return j.episodeID == ref.EpisodeID })
+		if i < 0 {
+			return fmt.Errorf("%w: episode %s in season %s", bilibili.ErrNotFound, ref.EpisodeID, id)
+		}
+		jobs = jobs[i : i+1]
+	}
+
+	if matchDir == "" {
+		return runJobs(ctx, jobs)
+	}
+
+	videos, err := findVideos(matchDir)
+	if err != nil {
+		return err
+	}
+
+	jobs, lostVideos, lostJobs := matchVideos(videos, jobs)
+	err = runJobs(ctx, jobs)
+	if !quiet {
+		renderUnmatched(lostVideos, lostJobs)
+	}
+	return err
+}
+
+// episodeIDs returns the episode IDs of items for --dlepisode: bare IDs
+// or episode links.
+func episodeIDs(ctx context.Context, items []batchItem) ([]string, error) {
+	ids := make([]string, 0, len(items))
+	for _, it := range items {
+		s := it.arg
+		if _, err := strconv.Atoi(s); err == nil {
+			ids = append(ids, s)
+			continue
+		}
+
+		ref, err := client.ResolveRef(ctx, s)
+		if err != nil {
+			return nil, fmt.Errorf("[ID: %s] %w", s, err)
+		}
+		if !ref.IsEpisode() {
+			return nil, fmt.Errorf("[ID: %s] not an episode link", s)
+		}
+		ids = append(ids, ref.EpisodeID)
+	}
+	return ids, nil
 }
 
-func runDlEpisode(ids []string) error {
-	var filename string
+func runDlEpisode(ctx context.Context, ids []string) error {
+	var (
+		filename string
+		jobs     []dlJob
+	)
 	if output != "" {
 		if err := os.MkdirAll(output, 0700); os.IsExist(err) {
 			return err
@@ -218,103 +471,270 @@ func runDlEpisode(ids []string) error {
 			filename = fmt.Sprintf(epFilename, i+1)
 		}
 
-		if err := downloadSub(id, filename, time.Now()); err != nil {
-			return err
-		}
+		jobs = append(jobs, dlJob{
+			episodeID:   id,
+			filename:    filename,
+			publishTime: time.Now(),
+			fields:      nameFields{EpisodeNumber: i + 1, EpisodeID: id, PublishTime: time.Now()},
+		})
 	}
-	return nil
+	return runJobs(ctx, jobs)
 }
 
-func downloadSub(episodeId, filename string, publishTime time.Time) error {
-	outFile := filepath.Join(output, filename)
-
-	if fastCheck {
-		for _, k := range []string{".srt", ".ass"} {
-			if _, err := os.Stat(outFile + k); !os.IsNotExist(err) && !overwrite && !quiet {
-				fmt.Println(color.HiBlackString("# %s", filename+k), color.HiYellowString("fast-check"))
-				return nil
-			}
-		}
+func downloadSub(ctx context.Context, w io.Writer, j dlJob) error {
+	if fastCheck && isFastChecked(w, j) {
+		return nil
 	}
 
-	episode, err := bilibili.GetApi(new(bilibili.EpisodeFile), bilibili.BilibiliSubtitleAPI, map[string]string{"ep_id": episodeId})
+	episode, err := client.Subtitles(ctx, j.episodeID)
 	if err != nil {
 		return err
 	}
 
+	if len(dualLanguages) > 0 {
+		return saveDual(ctx, w, j, episode.Data.Subtitles)
+	}
+
+	if len(languageFallback) > 0 {
+		k, ok := pickFallback(episode.Data.Subtitles)
+		if !ok {
+			fmt.Fprintln(w, color.HiBlackString("? %s", j.filename), color.HiRedString("missing %s", strings.Join(languageFallback, ",")))
+			summary.add(statusMissing)
+			return nil
+		}
+		if !quiet {
+			fmt.Fprintln(w, color.CyanString("> %s", j.filename), color.HiCyanString("using %s (%s)%s", k.Key, k.Title, machineNote(k)))
+		}
+		return saveSub(ctx, w, j, k)
+	}
+
+	var found []string
 	for _, k := range episode.Data.Subtitles {
-		if k.Key == language {
-			if k.IsMachine {
-				if skipMachine {
-					color.Yellow("- %s", filename)
-					return nil
-				}
-				color.Red("Warning: The downloaded subtitle has been machine translated and may contain errors or inaccuracies")
+		if slices.Contains(languages, allLanguages) || slices.Contains(languages, k.Key) {
+			found = append(found, k.Key)
+			if err := saveSub(ctx, w, j, k); err != nil {
+				return err
 			}
+		}
+	}
+
+	if slices.Contains(languages, allLanguages) {
+		if len(found) == 0 {
+			fmt.Fprintln(w, color.HiBlackString("? %s", j.filename), color.HiRedString("missing"))
+			summary.add(statusMissing)
+		}
+		return nil
+	}
+
+	for _, lang := range languages {
+		if !slices.Contains(found, lang) {
+			fmt.Fprintln(w, color.HiBlackString("? %s", j.trackName(bilibili.SubtitleTrack{Key: lang})), color.HiRedString("missing"))
+			summary.add(statusMissing)
+		}
+	}
+	return nil
+}
+
+// isFastChecked reports whether the subtitles of job j already exist
+// without asking the API. With --language every language has to exist,
+// with --language-fallback any language of the chain is enough.
+func isFastChecked(w io.Writer, j dlJob) bool {
+	if slices.Contains(languages, allLanguages) || overwrite || quiet {
+		return false
+	}
+
+	if len(dualLanguages) > 0 {
+		if _, err := os.Stat(filepath.Join(output, dualName(j))); os.IsNotExist(err) {
+			return false
+		}
+		fmt.Fprintln(w, color.HiBlackString("# %s", dualName(j)), color.HiYellowString("fast-check"))
+		summary.add(statusExisted)
+		return true
+	}
+
+	candidates := languages
+	if len(candidates) == 0 {
+		candidates = languageFallback
+	}
+
+	var existed []string
+	for _, lang := range candidates {
+		name := j.trackName(bilibili.SubtitleTrack{Key: lang}) + "." + format
+		if _, err := os.Stat(filepath.Join(output, name)); !os.IsNotExist(err) {
+			existed = append(existed, name)
+		}
+	}
+
+	if len(existed) == 0 || len(languages) > 0 && len(existed) < len(languages) {
+		return false
+	}
 
-			fileType := filepath.Ext(strings.Split(k.URL, "?")[0])
-			if fileType == ".json" {
-				fileType = ".srt"
+	for _, s := range existed {
+		fmt.Fprintln(w, color.HiBlackString("# %s", s), color.HiYellowString("fast-check"))
+		summary.add(statusExisted)
+	}
+	return true
+}
+
+// pickFallback returns the first track of tracks following the order of
+// --language-fallback. With --prefer-human a human translation anywhere in
+// the chain beats a machine translation earlier in it.
+func pickFallback(tracks []bilibili.SubtitleTrack) (bilibili.SubtitleTrack, bool) {
+	var machine *bilibili.SubtitleTrack
+	for _, lang := range languageFallback {
+		for i, k := range tracks {
+			if k.Key != lang || k.IsMachine && skipMachine {
+				continue
 			}
-			if dlArchive != "" {
-				isInArchive, err := checkArchive(strconv.Itoa(k.ID))
-				if err != nil {
-					return err
-				}
-				if isInArchive && !overwrite {
-					fmt.Println(color.HiBlackString("# %s", filename+fileType), color.HiYellowString("archived"))
-					return nil
-				}
-				if _, err := os.Stat(outFile + fileType); !os.IsNotExist(err) && !overwrite {
-					err = add2Archive(strconv.Itoa(k.ID))
-					if err != nil {
-						return err
-					}
-					fmt.Println(color.HiBlackString("# %s", filename+fileType), color.HiYellowString("existed, add to archive"))
-					return nil
-				}
-			} else if _, err := os.Stat(outFile + fileType); !os.IsNotExist(err) && !overwrite {
-				fmt.Println(color.HiBlackString("# %s", filename+fileType), color.HiYellowString("existed"))
-				return nil
+			if !k.IsMachine || !preferHuman {
+				return k, true
 			}
-
-			if err := os.MkdirAll(filepath.Dir(outFile), 0o700); err != nil {
-				return err
+			if machine == nil {
+				machine = &tracks[i]
 			}
+		}
+	}
+
+	if machine != nil {
+		return *machine, true
+	}
+	return bilibili.SubtitleTrack{}, false
+}
+
+func machineNote(k bilibili.SubtitleTrack) string {
+	if k.IsMachine {
+		return ", machine translated"
+	}
+	return ""
+}
+
+func saveSub(ctx context.Context, w io.Writer, j dlJob, k bilibili.SubtitleTrack) error {
+	filename := j.trackName(k)
+	if k.IsMachine {
+		if skipMachine {
+			fmt.Fprintln(w, color.YellowString("- %s", filename))
+			summary.add(statusSkippedMachine)
+			return nil
+		}
+		fmt.Fprintln(w, color.RedString("Warning: The downloaded subtitle has been machine translated and may contain errors or inaccuracies"))
+	}
+
+	fileType := filepath.Ext(strings.Split(k.URL, "?")[0])
+	from, err := bilibili.SubtitleFormat(k.URL)
+	convert := err == nil
+	if convert {
+		fileType = "." + format
+	}
+
+	entry := archiveEntry{
+		SubtitleID: strconv.Itoa(k.ID),
+		EpisodeID:  j.episodeID,
+		SeasonID:   j.fields.SeasonID,
+		Lang:       k.Key,
+		IsMachine:  k.IsMachine,
+	}
+	return writeSub(w, entry, filename+fileType, j.publishTime, func() ([]byte, []byte, error) {
+		source, err := fetchSub(ctx, j.episodeID, k, filename, j.publishTime)
+		if err != nil || !convert {
+			return source, source, err
+		}
+
+		var sub []byte
+		if from == subtitle.Format(format) {
+			sub, err = subtitle.RetimeFile(source, from, timing())
+		} else {
+			sub, err = renderSub(source, from, subtitle.Format(format))
+		}
+		return sub, source, err
+	})
+}
+
+// fetchFunc downloads a subtitle and returns the file to write together
+// with the upstream payload it was rendered from.
+type fetchFunc func() (sub, source []byte, err error)
 
-			sub, err := bilibili.GetSubtitle(k.URL, fileType)
+// writeSub writes the subtitle returned by fetch to filename unless the
+// file exists or the subtitle of entry is in the download archive. entry is
+// completed and recorded in the archive once the file is written.
+func writeSub(w io.Writer, entry archiveEntry, filename string, publishTime time.Time, fetch fetchFunc) error {
+	outFile := filepath.Join(output, filename)
+	entry.Path = outFile
+
+	if update {
+		if _, err := os.Stat(outFile); !os.IsNotExist(err) || archive != nil && archive.has(entry.SubtitleID) {
+			return updateSub(w, entry, filename, publishTime, fetch)
+		}
+	}
+
+	if archive != nil {
+		if archive.has(entry.SubtitleID) && !overwrite {
+			fmt.Fprintln(w, color.HiBlackString("# %s", filename), color.HiYellowString("archived"))
+			summary.add(statusArchived)
+			return nil
+		}
+		if data, err := os.ReadFile(outFile); !os.IsNotExist(err) && !overwrite {
 			if err != nil {
 				return err
 			}
-
-			if err := utils.WriteFile(outFile+fileType, sub, publishTime); err != nil {
+			entry.Hash, entry.Time = contentHash(data), time.Now()
+			if err := archive.add(entry, false); err != nil {
 				return err
 			}
+			fmt.Fprintln(w, color.HiBlackString("# %s", filename), color.HiYellowString("existed, add to archive"))
+			summary.add(statusExisted)
+			return nil
+		}
+	} else if _, err := os.Stat(outFile); !os.IsNotExist(err) && !overwrite {
+		fmt.Fprintln(w, color.HiBlackString("# %s", filename), color.HiYellowString("existed"))
+		summary.add(statusExisted)
+		return nil
+	}
 
-			if dlArchive != "" {
-				isInArchive, err := checkArchive(strconv.Itoa(k.ID))
-				if err != nil {
-					return err
-				}
-				if !isInArchive {
-					err = add2Archive(strconv.Itoa(k.ID))
-					if err != nil {
-						return err
-					}
-				}
-			}
+	if err := os.MkdirAll(filepath.Dir(outFile), 0o700); err != nil {
+		return err
+	}
 
-			if !quiet {
-				color.Green("* %s", filename+fileType)
-			}
-			break
+	sub, source, err := fetch()
+	if err != nil {
+		return err
+	}
+
+	if err := utils.WriteFile(outFile, sub, publishTime); err != nil {
+		return err
+	}
+
+	if archive != nil {
+		entry.Hash, entry.SourceHash, entry.Time = contentHash(sub), contentHash(source), time.Now()
+		if err := archive.add(entry, overwrite); err != nil {
+			return err
 		}
 	}
+
+	summary.add(statusDownloaded)
+	if !quiet {
+		fmt.Fprintln(w, color.GreenString("* %s", filename))
+	}
 	return nil
 }
 
-func runTimeline(day string) error {
-	tl, err := bilibili.GetApi(new(bilibili.Timeline), bilibili.BilibiliTimelineAPI, nil)
+// renderSub converts data from format from to format to, correcting its
+// timing on the way. Subtitles without styles of their own get the style set
+// by the --ass-* flags.
+func renderSub(data []byte, from, to subtitle.Format) ([]byte, error) {
+	sub, err := subtitle.Unmarshal(data, from)
+	if err != nil {
+		return nil, err
+	}
+	sub.Retime(timing())
+
+	if len(sub.Styles) == 0 {
+		sub.Styles = []subtitle.Style{assStyle}
+	}
+	return subtitle.Marshal(sub, to)
+}
+
+func runTimeline(ctx context.Context, day string) error {
+	tl, err := client.Timeline(ctx)
 	if err != nil {
 		return err
 	}
@@ -349,16 +769,8 @@ func runTimeline(day string) error {
 	return nil
 }
 
-func runSearch(s string) error {
-	query := map[string]string{
-		"keyword":  s,
-		"platform": "web",
-		"pn":       "1",
-		"ps":       "20",
-		"s_locale": "en_US",
-	}
-
-	ss, err := bilibili.GetApi(new(bilibili.Search), bilibili.BilibiliSearchAPI, query)
+func runSearch(ctx context.Context, s string) error {
+	ss, err := client.Search(ctx, s)
 	if err != nil {
 		return err
 	}
@@ -384,18 +796,13 @@ func runSearch(s string) error {
 	return nil
 }
 
-func runList(id string) error {
-	query := map[string]string{
-		"s_locale":  "en_US",
-		"season_id": id,
-	}
-
-	info, err := bilibili.GetApi(new(bilibili.Info), bilibili.BilibiliSeasonInfoAPI, query)
+func runList(ctx context.Context, id string) error {
+	info, err := client.SeasonInfo(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	epList, err := bilibili.GetApi(new(bilibili.Episodes), bilibili.BilibiliEpisodeInfoAPI, query)
+	epList, err := client.Episodes(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -409,8 +816,12 @@ func runList(id string) error {
 
 	switch {
 	case listLang:
-		episode, err := bilibili.GetApi(new(bilibili.EpisodeFile), bilibili.BilibiliSubtitleAPI, map[string]string{"ep_id": epList.Data.Sections[0].Episodes[0].EpisodeID.String()})
+		eps := bilibili.ExtractEp(epList.Data.Sections, nil, nil)
+		if len(eps) == 0 {
+			return fmt.Errorf("The list is currently empty. Please check back later.")
+		}
 
+		episode, err := client.Subtitles(ctx, eps[0].EpisodeID.String())
 		if err != nil {
 			return err
 		}
@@ -443,37 +854,3 @@ func newTable(header []string) *tablewriter.Table {
 	table.SetHeader(header)
 	return table
 }
-
-func checkArchive(subtitleID string) (bool, error) {
-	f, err := os.Open(dlArchive)
-	defer f.Close()
-	if os.IsNotExist(err) {
-		return false, nil
-	}
-
-	if err != nil {
-		return false, err
-	}
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		if subtitleID == scanner.Text() {
-			return true, nil
-		}
-	}
-	return false, nil
-}
-
-func add2Archive(subtitleID string) error {
-	f, err := os.OpenFile(dlArchive, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o700)
-	defer f.Close()
-	if err != nil {
-		return err
-	}
-
-	if _, err := f.WriteString(subtitleID + "\n"); err != nil {
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+)
+
+// fakeSeason serves season 1 with episodes 101 to 103 in one section.
+// Subtitle lists and files are left to handler.
+func fakeSeason(t *testing.T, handler http.HandlerFunc) {
+	useTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/web/v2/ogv/play/season_info":
+			fmt.Fprint(w, `{"code":0,"data":{"season":{"title":"Show"}}}`)
+		case "/web/v2/ogv/play/episodes":
+			fmt.Fprint(w, `{"code":0,"data":{"sections":[{"title":"S1","episodes":[
+{"episode_id":101,"title_display":"E1"},{"episode_id":102,"title_display":"E2"},{"episode_id":103,"title_display":"E3"}]}]}}`)
+		default:
+			handler(w, r)
+		}
+	})
+}
+
+func TestRunDlEpisodeLinkIgnoresRanges(t *testing.T) {
+	defer func(o string, l, e []string, q bool) { output, languages, episodeSelect, quiet = o, l, e, q }(output, languages, episodeSelect, quiet)
+	output, languages, episodeSelect, quiet = t.TempDir(), []string{"en"}, []string{"1"}, true
+
+	var (
+		mu       sync.Mutex
+		episodes []string
+	)
+	fakeSeason(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		episodes = append(episodes, r.URL.Query().Get("ep_id"))
+		mu.Unlock()
+		fmt.Fprint(w, `{"code":0,"data":{"subtitles":[]}}`)
+	})
+
+	if err := runDl(context.Background(), bilibili.Ref{SeasonID: "1", EpisodeID: "102"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(episodes, []string{"102"}) {
+		t.Errorf("requested episodes %v, want [102]", episodes)
+	}
+}
+
+func TestPickFallback(t *testing.T) {
+	defer func(f []string, p, s bool) { languageFallback, preferHuman, skipMachine = f, p, s }(languageFallback, preferHuman, skipMachine)
+	languageFallback = []string{"en", "zh-Hans", "th"}
+
+	enMachine := bilibili.SubtitleTrack{Key: "en", IsMachine: true}
+	zhMachine := bilibili.SubtitleTrack{Key: "zh-Hans", IsMachine: true}
+	th := bilibili.SubtitleTrack{Key: "th"}
+	tests := []struct {
+		name        string
+		tracks      []bilibili.SubtitleTrack
+		preferHuman bool
+		skipMachine bool
+		want        string
+		wantOk      bool
+	}{
+		{name: "chain order", tracks: []bilibili.SubtitleTrack{th, enMachine}, want: "en", wantOk: true},
+		{name: "human later in the chain", tracks: []bilibili.SubtitleTrack{enMachine, th}, preferHuman: true, want: "th", wantOk: true},
+		{name: "machine kept without human", tracks: []bilibili.SubtitleTrack{zhMachine, enMachine}, preferHuman: true, want: "en", wantOk: true},
+		{name: "machine skipped", tracks: []bilibili.SubtitleTrack{enMachine, th}, skipMachine: true, want: "th", wantOk: true},
+		{name: "only machine skipped", tracks: []bilibili.SubtitleTrack{enMachine, zhMachine}, preferHuman: true, skipMachine: true},
+		{name: "none in the chain", tracks: []bilibili.SubtitleTrack{{Key: "id"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preferHuman, skipMachine = tt.preferHuman, tt.skipMachine
+			got, ok := pickFallback(tt.tracks)
+			if got.Key != tt.want || ok != tt.wantOk {
+				t.Errorf("pickFallback() = %q, %t, want %q, %t", got.Key, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
+	"github.com/K0ng2/bilisubdl/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert [FILE|DIR|GLOB]... [flags]",
+	Short: "command converts local subtitle files (JSON, ASS, SRT or VTT) without downloading them again.",
+	Long: `
+Converts subtitle files to another format, writing the result next to the
+source file with the new extension and the same modification time.
+Directories are searched recursively for subtitle files and glob patterns
+(e.g. 'Show/*.json') are expanded.
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, err := parseConvertFormat(convertTo)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+
+		files, err := convertFiles(args, to)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, s := range files {
+			if err := runConvert(s, to); err != nil {
+				fmt.Fprintln(color.Output, color.RedString("! %s: %s", s, err))
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d file(s) failed to convert", failed, len(files))
+		}
+		return nil
+	},
+	Example: "bilisubdl convert \"Episode 1.en.json\" --to srt\nbilisubdl convert ./Show --to vtt\nbilisubdl convert \"Show/*.ass\" --to txt",
+}
+
+// parseConvertFormat parses the value of --to, with or without a leading
+// dot. JSON is only read, TXT only written.
+func parseConvertFormat(s string) (subtitle.Format, error) {
+	if f := subtitle.Format(strings.ToLower(strings.TrimPrefix(s, "."))); f == subtitle.TXT {
+		return f, nil
+	}
+	f, err := subtitle.ParseFormat(s)
+	if err != nil || f == subtitle.JSON {
+		return "", fmt.Errorf("invalid format %q, must be one of srt, vtt, ass, txt", s)
+	}
+	return f, nil
+}
+
+// convertFiles expands args into the subtitle files to convert to format
+// to. Files already in that format, the track metadata of --keep-raw and,
+// inside directories, hidden files such as the --resume checkpoint are left
+// out.
+func convertFiles(args []string, to subtitle.Format) ([]string, error) {
+	var files []string
+	add := func(name string) {
+		if strings.HasSuffix(name, rawMetaExt) {
+			return
+		}
+		if f, err := subtitle.ParseFormat(filepath.Ext(name)); err == nil && f != to {
+			files = append(files, name)
+		}
+	}
+
+	for _, arg := range args {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("[file: %s] %w", arg, os.ErrNotExist)
+		}
+
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(m)
+				continue
+			}
+
+			err = filepath.WalkDir(m, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				hidden := path != m && strings.HasPrefix(d.Name(), ".")
+				if d.IsDir() {
+					if hidden {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !hidden {
+					add(path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// runConvert converts filename to format to and writes it next to the
+// source with the same modification time.
+func runConvert(filename string, to subtitle.Format) error {
+	from, err := subtitle.ParseFormat(filepath.Ext(filename))
+	if err != nil {
+		return err
+	}
+
+	outFile := strings.TrimSuffix(filename, filepath.Ext(filename)) + "." + string(to)
+	if _, err := os.Stat(outFile); !os.IsNotExist(err) && !overwrite {
+		fmt.Println(color.HiBlackString("# %s", outFile), color.HiYellowString("existed"))
+		return nil
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	sub, err := renderSub(data, from, to)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.WriteFile(outFile, sub, info.ModTime()); err != nil {
+		return err
+	}
+
+	color.Green("* %s", outFile)
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
+)
+
+func TestParseConvertFormat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    subtitle.Format
+		wantErr bool
+	}{
+		{value: "srt", want: subtitle.SRT},
+		{value: ".srt", want: subtitle.SRT},
+		{value: "VTT", want: subtitle.VTT},
+		{value: "txt", want: subtitle.TXT},
+		{value: ".TXT", want: subtitle.TXT},
+		{value: "json", wantErr: true},
+		{value: "doc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseConvertFormat(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseConvertFormat(%q) = %q, %v, want %q, error %t", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestConvertFilesRaw(t *testing.T) {
+	defer func(o string) { output = o }(output)
+	output = t.TempDir()
+
+	k := bilibili.SubtitleTrack{Key: "en", URL: "https://example.com/sub/101-en.json?auth_key=1"}
+	if err := saveRaw("101", k, "Show/E1.en", []byte(`{"body":[]}`), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(output, checkpointName), []byte(`{"args":[],"done":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := convertFiles([]string{output}, subtitle.SRT); err != nil || len(got) != 0 {
+		t.Errorf("convertFiles() = %q, %v, want hidden files and folders left out", got, err)
+	}
+
+	raw := filepath.Join(output, "Show", rawDir)
+	got, err := convertFiles([]string{raw}, subtitle.SRT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{filepath.Join(raw, "E1.en.json")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("convertFiles() = %q, want %q", got, want)
+	}
+}
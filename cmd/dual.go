@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
+	"github.com/fatih/color"
+)
+
+// dualName returns the filename of the bilingual subtitle for --dual, the
+// languages joined by "+" in place of one language.
+func dualName(j dlJob) string {
+	return j.trackName(bilibili.SubtitleTrack{Key: strings.Join(dualLanguages, "+")}) + "." + format
+}
+
+// saveDual writes the two tracks selected by --dual merged into one file:
+// stacked in one cue for SRT and VTT, as a bottom and a top style for ASS.
+func saveDual(ctx context.Context, w io.Writer, j dlJob, tracks []bilibili.SubtitleTrack) error {
+	var pair [2]bilibili.SubtitleTrack
+	for i, lang := range dualLanguages {
+		var ok bool
+		for _, k := range tracks {
+			if k.Key == lang {
+				pair[i], ok = k, true
+				break
+			}
+		}
+
+		if !ok {
+			fmt.Fprintln(w, color.HiBlackString("? %s", dualName(j)), color.HiRedString("missing %s", lang))
+			summary.add(statusMissing)
+			return nil
+		}
+
+		if pair[i].IsMachine {
+			if skipMachine {
+				fmt.Fprintln(w, color.YellowString("- %s", dualName(j)))
+				summary.add(statusSkippedMachine)
+				return nil
+			}
+			fmt.Fprintln(w, color.RedString("Warning: The %s subtitle has been machine translated and may contain errors or inaccuracies", lang))
+		}
+	}
+
+	entry := archiveEntry{
+		SubtitleID: fmt.Sprintf("%d+%d", pair[0].ID, pair[1].ID),
+		EpisodeID:  j.episodeID,
+		SeasonID:   j.fields.SeasonID,
+		Lang:       strings.Join(dualLanguages, "+"),
+		IsMachine:  pair[0].IsMachine || pair[1].IsMachine,
+	}
+	return writeSub(w, entry, dualName(j), j.publishTime, func() ([]byte, []byte, error) {
+		var (
+			subs   [2]*subtitle.Subtitle
+			source []byte
+		)
+		for i, k := range pair {
+			sub, data, err := fetchDual(ctx, j, k)
+			if err != nil {
+				return nil, nil, fmt.Errorf("[%s] %w", k.Key, err)
+			}
+			sub.Retime(timing())
+			subs[i] = sub
+			source = append(source, data...)
+		}
+
+		merged, err := mergeSubs(subs[0], subs[1])
+		return merged, source, err
+	})
+}
+
+// fetchDual downloads and decodes one of the two tracks of --dual. The file
+// as downloaded is returned too.
+func fetchDual(ctx context.Context, j dlJob, k bilibili.SubtitleTrack) (*subtitle.Subtitle, []byte, error) {
+	f, err := bilibili.SubtitleFormat(k.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := fetchSub(ctx, j.episodeID, k, j.trackName(k), j.publishTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := subtitle.Unmarshal(data, f)
+	return sub, data, err
+}
+
+func mergeSubs(primary, secondary *subtitle.Subtitle) ([]byte, error) {
+	if subtitle.Format(format) != subtitle.ASS {
+		return subtitle.Marshal(subtitle.Merge(primary, secondary), subtitle.Format(format))
+	}
+
+	top := assStyle
+	top.Name, top.Alignment = "Secondary", 8
+	return subtitle.Marshal(subtitle.MergeStyled(primary, secondary, assStyle, top), subtitle.ASS)
+}
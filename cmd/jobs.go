@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+	"github.com/fatih/color"
+)
+
+// dlJob is one episode to download. filename names the episode in the
+// output and, without --output-template, is the subtitle path without the
+// language and extension.
+type dlJob struct {
+	episodeID   string
+	filename    string
+	publishTime time.Time
+	fields      nameFields
+}
+
+// trackName returns the path of the subtitle for track k, relative to
+// --output and without extension.
+func (j dlJob) trackName(k bilibili.SubtitleTrack) string {
+	if outTmpl != nil {
+		return outTmpl.expand(j.fields, k)
+	}
+	return j.filename + "." + k.Key
+}
+
+// errNotStarted marks the jobs left out when dl stopped early.
+var errNotStarted = errors.New("not started")
+
+// jobErrors collects the errors of the episodes that failed in a run.
+type jobErrors []error
+
+func (e jobErrors) Error() string {
+	msg := make([]string, 0, len(e)+1)
+	msg = append(msg, fmt.Sprintf("%d episode(s) failed:", len(e)))
+	for _, err := range e {
+		msg = append(msg, "  "+err.Error())
+	}
+	return strings.Join(msg, "\n")
+}
+
+// runJobs downloads jobs over a pool of concurrency workers. The output of
+// every job is buffered and printed in job order, and a failing job does not
+// stop the others; their errors are returned together at the end. Jobs done
+// by an earlier run are skipped with --resume, the others are recorded in
+// the checkpoint as they finish. After a signal or once --timeout expired no
+// new job is started.
+func runJobs(ctx context.Context, jobs []dlJob) error {
+	todo := make([]dlJob, 0, len(jobs))
+	for _, j := range jobs {
+		if !ckpt.isDone(j) {
+			todo = append(todo, j)
+		}
+	}
+	if n := len(jobs) - len(todo); n > 0 && !quiet {
+		fmt.Fprintln(color.Output, color.HiBlackString("# %d episode(s) done in an earlier run", n))
+	}
+	jobs = todo
+
+	workers := concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		queue = make(chan int)
+		outs  = make([]bytes.Buffer, len(jobs))
+		errs  = make([]error, len(jobs))
+		done  = make([]chan struct{}, len(jobs))
+	)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				errs[i] = downloadSub(ctx, &outs[i], jobs[i])
+				close(done[i])
+			}
+		}()
+	}
+
+	go func() {
+		defer close(queue)
+		for i := range jobs {
+			if stopped(ctx) == nil {
+				select {
+				case queue <- i:
+					continue
+				case <-interrupted:
+				case <-ctx.Done():
+				}
+			}
+
+			for ; i < len(jobs); i++ {
+				errs[i] = errNotStarted
+				close(done[i])
+			}
+			return
+		}
+	}()
+
+	var (
+		failed     jobErrors
+		notStarted int
+	)
+	for i, j := range jobs {
+		<-done[i]
+		if errs[i] == errNotStarted {
+			notStarted++
+			continue
+		}
+		color.Output.Write(outs[i].Bytes())
+		if errs[i] != nil {
+			fmt.Fprintln(color.Output, color.RedString("! %s: %s", j.filename, errs[i]))
+			summary.add(statusFailed)
+			failed = append(failed, fmt.Errorf("[episode: %s] %w", j.episodeID, errs[i]))
+		} else if err := ckpt.markDone(j); err != nil {
+			failed = append(failed, fmt.Errorf("[episode: %s] %w", j.episodeID, err))
+		}
+	}
+	wg.Wait()
+
+	if notStarted > 0 {
+		return fmt.Errorf("%w, %d episode(s) not started, run again with --resume to continue", stopped(ctx), notStarted)
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
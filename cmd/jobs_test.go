@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func TestRunJobsOrder(t *testing.T) {
+	defer func(o string, l []string, c int, q bool, w io.Writer) {
+		output, languages, concurrency, quiet, color.Output = o, l, c, q, w
+	}(output, languages, concurrency, quiet, color.Output)
+	var out bytes.Buffer
+	output, languages, concurrency, quiet, color.Output = t.TempDir(), []string{"en"}, 3, false, &out
+
+	// The first episode answers only once the last one got its subtitle.
+	lastDone := make(chan struct{})
+	var baseURL string
+	useTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch ep := r.URL.Query().Get("ep_id"); {
+		case strings.HasPrefix(r.URL.Path, "/sub/"):
+			fmt.Fprint(w, `{"body":[{"from":1,"to":2,"content":"Hello"}]}`)
+			if r.URL.Path == "/sub/103.json" {
+				close(lastDone)
+			}
+		case ep == "102":
+			fmt.Fprint(w, `{"code":-404,"message":"not found"}`)
+		default:
+			if ep == "101" {
+				select {
+				case <-lastDone:
+				case <-time.After(5 * time.Second):
+					t.Error("episode 103 did not run while 101 was in progress")
+				}
+			}
+			fmt.Fprintf(w, `{"code":0,"data":{"subtitles":[{"url":"%s/sub/%s.json","id":1,"key":"en"}]}}`, baseURL, ep)
+		}
+	})
+	baseURL = client.BaseURL
+
+	jobs := []dlJob{{episodeID: "101", filename: "E1"}, {episodeID: "102", filename: "E2"}, {episodeID: "103", filename: "E3"}}
+	err := runJobs(context.Background(), jobs)
+
+	var failed jobErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || !strings.Contains(failed[0].Error(), "102") {
+		t.Errorf("runJobs() error = %v, want episode 102 failed", err)
+	}
+
+	got := out.String()
+	e1, e2, e3 := strings.Index(got, "* E1.en.srt"), strings.Index(got, "! E2:"), strings.Index(got, "* E3.en.srt")
+	if e1 < 0 || e2 < e1 || e3 < e2 {
+		t.Errorf("output = %q, want E1, E2 and E3 in job order", got)
+	}
+}
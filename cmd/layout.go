@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+)
+
+// Values of --layout.
+const (
+	layoutDefault     = "default"
+	layoutMediaServer = "mediaserver"
+)
+
+// layouts maps --layout presets to the output template they stand for.
+var layouts = map[string]string{
+	layoutDefault: "",
+	// Plex, Jellyfin and Kodi match "Show/Season 01/Show - S01E05.en.srt".
+	layoutMediaServer: "{season_title}/Season {season_number:02d}/{season_title} - S{season_number:02d}E{season_episode:02d}.{lang}{lang_flags}",
+}
+
+// specialSection matches the titles of sections that hold specials rather
+// than a season, such as PVs, OVAs or trailers.
+var specialSection = regexp.MustCompile(`(?i)\b(pv|cm|sp|ova|oad|specials?|extras?|bonus|trailers?|teasers?|previews?)\b|特别|特典|预告|花絮|番外`)
+
+// seasonNumbers returns the season of every section for media servers and
+// the number of episodes before it in that season: specials of all sections
+// go to season 0 one after another, the other sections are numbered in
+// order from 1.
+func seasonNumbers(sections []bilibili.Section) (seasons, offsets []int) {
+	seasons = make([]int, len(sections))
+	offsets = make([]int, len(sections))
+	season, specials := 0, 0
+	for i, s := range sections {
+		if specialSection.MatchString(s.Title) {
+			offsets[i] = specials
+			specials += len(s.Episodes)
+			continue
+		}
+		season++
+		seasons[i] = season
+	}
+	return seasons, offsets
+}
+
+var (
+	forcedTrack = regexp.MustCompile(`(?i)\bforced\b`)
+	sdhTrack    = regexp.MustCompile(`(?i)\b(sdh|cc|hard of hearing)\b`)
+)
+
+// langFlags returns the suffixes media servers read after the language for
+// subtitles for the deaf and hard of hearing and forced subtitles.
+func langFlags(k bilibili.SubtitleTrack) string {
+	switch {
+	case forcedTrack.MatchString(k.Title):
+		return ".forced"
+	case sdhTrack.MatchString(k.Title):
+		return ".sdh"
+	}
+	return ""
+}
+
+// layoutTemplate returns the output template for --layout and
+// --output-template.
+func layoutTemplate() (string, error) {
+	tmpl, ok := layouts[layout]
+	if !ok {
+		return "", fmt.Errorf("invalid layout %q, must be one of %s, %s", layout, layoutDefault, layoutMediaServer)
+	}
+	if tmpl != "" && dlepisode {
+		return "", fmt.Errorf("--layout %s needs an anime ID and does not work with --dlepisode", layout)
+	}
+	if outTemplate != "" {
+		return outTemplate, nil
+	}
+	return tmpl, nil
+}
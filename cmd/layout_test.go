@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+)
+
+func TestSeasonNumbers(t *testing.T) {
+	sections := []bilibili.Section{
+		{Title: "PV", Episodes: make([]bilibili.Episode, 2)},
+		{Title: "Episodes", Episodes: make([]bilibili.Episode, 12)},
+		{Title: "OVA", Episodes: make([]bilibili.Episode, 1)},
+		{Title: "Part 2", Episodes: make([]bilibili.Episode, 12)},
+	}
+
+	seasons, offsets := seasonNumbers(sections)
+	if want := []int{0, 1, 0, 2}; !reflect.DeepEqual(seasons, want) {
+		t.Errorf("seasons = %v, want %v", seasons, want)
+	}
+	if want := []int{0, 0, 2, 0}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("offsets = %v, want %v", offsets, want)
+	}
+}
+
+func TestMediaServerLayout(t *testing.T) {
+	tmpl, err := parseTemplate(layouts[layoutMediaServer])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := nameFields{SeasonTitle: "Show Name", SeasonNumber: 1, SeasonEpisode: 5}
+	tests := []struct {
+		track bilibili.SubtitleTrack
+		want  string
+	}{
+		{bilibili.SubtitleTrack{Key: "en", Title: "English"}, "Show Name/Season 01/Show Name - S01E05.en"},
+		{bilibili.SubtitleTrack{Key: "en", Title: "English (SDH)"}, "Show Name/Season 01/Show Name - S01E05.en.sdh"},
+		{bilibili.SubtitleTrack{Key: "en", Title: "English (Forced)"}, "Show Name/Season 01/Show Name - S01E05.en.forced"},
+	}
+	for _, tt := range tests {
+		if got := tmpl.expand(fields, tt.track); got != tt.want {
+			t.Errorf("expand(%q) = %q, want %q", tt.track.Title, got, tt.want)
+		}
+	}
+}
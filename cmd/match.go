@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// videoExts lists the extensions --match-dir takes for video files.
+var videoExts = []string{".mkv", ".mp4", ".m4v", ".avi", ".mov", ".webm", ".ts", ".flv", ".wmv"}
+
+var (
+	seasonEpisodeName = regexp.MustCompile(`(?i)\bS(\d{1,2})[ ._-]?E(\d{1,4})\b`)
+	// episodeNames are tried in order on names without SxxEyy.
+	episodeNames = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:E|EP|Episode)[ ._-]?(\d{1,4})(?:v\d)?\b`),
+		regexp.MustCompile(`\s-\s(\d{1,4})(?:v\d)?\b`),
+		regexp.MustCompile(`\[(\d{1,4})(?:v\d)?\]`),
+		regexp.MustCompile(`第(\d{1,4})[话話集]`),
+	}
+)
+
+// parseEpisodeName extracts the episode number from the name of a video
+// file. season is -1 when the name has no SxxEyy.
+func parseEpisodeName(name string) (season, episode int, ok bool) {
+	if m := seasonEpisodeName.FindStringSubmatch(name); m != nil {
+		season, _ = strconv.Atoi(m[1])
+		episode, _ = strconv.Atoi(m[2])
+		return season, episode, true
+	}
+
+	for _, re := range episodeNames {
+		if m := re.FindStringSubmatch(name); m != nil {
+			episode, _ = strconv.Atoi(m[1])
+			return -1, episode, true
+		}
+	}
+	return -1, 0, false
+}
+
+// findVideos returns the video files in dir and its subfolders, relative to
+// dir.
+func findVideos(dir string) ([]string, error) {
+	var videos []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if slices.Contains(videoExts, strings.ToLower(filepath.Ext(path))) {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			videos = append(videos, rel)
+		}
+		return nil
+	})
+	return videos, err
+}
+
+// matchVideos pairs videos with the episodes of jobs. A name with SxxEyy
+// matches the media server numbers of --layout mediaserver, any other
+// number counts the episodes of the seasons in order, leaving out specials.
+// Every matched video gets a job named after it; the videos and episodes
+// left over are returned as well.
+func matchVideos(videos []string, jobs []dlJob) (matched []dlJob, lostVideos []string, lostJobs []dlJob) {
+	used := make([]bool, len(jobs))
+	for _, v := range videos {
+		season, episode, ok := parseEpisodeName(filepath.Base(v))
+		i := -1
+		if ok {
+			i = slices.IndexFunc(jobs, func(j dlJob) bool {
+				if season < 0 {
+					return j.fields.SeasonNumber > 0 && j.fields.AbsoluteNumber == episode
+				}
+				return j.fields.SeasonNumber == season && j.fields.SeasonEpisode == episode
+			})
+		}
+		if i < 0 {
+			lostVideos = append(lostVideos, v)
+			continue
+		}
+
+		j := jobs[i]
+		j.filename = strings.TrimSuffix(v, filepath.Ext(v))
+		matched = append(matched, j)
+		used[i] = true
+	}
+
+	for i, j := range jobs {
+		if !used[i] {
+			lostJobs = append(lostJobs, j)
+		}
+	}
+	return matched, lostVideos, lostJobs
+}
+
+// renderUnmatched prints the videos and the episodes --match-dir could not
+// pair side by side.
+func renderUnmatched(videos []string, jobs []dlJob) {
+	if len(videos) == 0 && len(jobs) == 0 {
+		return
+	}
+
+	fmt.Println()
+	table := newTable([]string{"Unmatched video", "Unmatched episode"})
+	for i := 0; i < len(videos) || i < len(jobs); i++ {
+		var row [2]string
+		if i < len(videos) {
+			row[0] = videos[i]
+		}
+		if i < len(jobs) {
+			row[1] = fmt.Sprintf("S%02dE%02d %s", jobs[i].fields.SeasonNumber, jobs[i].fields.SeasonEpisode, jobs[i].filename)
+		}
+		table.Append(row[:])
+	}
+	table.Render()
+}
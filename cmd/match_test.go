@@ -0,0 +1,26 @@
+package cmd
+
+import "testing"
+
+func TestParseEpisodeName(t *testing.T) {
+	tests := []struct {
+		name            string
+		season, episode int
+		ok              bool
+	}{
+		{"Show Name - S01E05.mkv", 1, 5, true},
+		{"show.name.s02e11.1080p.mkv", 2, 11, true},
+		{"[Group] Show Name - 05 [1080p].mkv", -1, 5, true},
+		{"[Group] Show Name - 12v2 [ABCD1234].mkv", -1, 12, true},
+		{"[Group] Show Name [07][1080p].mp4", -1, 7, true},
+		{"Show Name EP03.mp4", -1, 3, true},
+		{"Show Name 第4话.mp4", -1, 4, true},
+		{"Show Name [1080p].mkv", -1, 0, false},
+	}
+	for _, tt := range tests {
+		season, episode, ok := parseEpisodeName(tt.name)
+		if season != tt.season || episode != tt.episode || ok != tt.ok {
+			t.Errorf("parseEpisodeName(%q) = %d, %d, %t, want %d, %d, %t", tt.name, season, episode, ok, tt.season, tt.episode, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+	"github.com/K0ng2/bilisubdl/utils"
+)
+
+// rawDir is the folder next to the converted subtitles where --keep-raw
+// stores what the API returned.
+const rawDir = ".raw"
+
+// rawMetaExt ends the name of the rawTrack file next to a raw subtitle.
+// convert skips these files.
+const rawMetaExt = ".meta.json"
+
+// rawTrack records the episode and track a raw subtitle was downloaded from.
+type rawTrack struct {
+	EpisodeID string                 `json:"episode_id"`
+	Track     bilibili.SubtitleTrack `json:"track"`
+}
+
+// fetchSub downloads the subtitle file of track k. With --keep-raw it also
+// stores the file untouched, together with the track metadata, in the .raw
+// folder next to filename.
+func fetchSub(ctx context.Context, episodeID string, k bilibili.SubtitleTrack, filename string, publishTime time.Time) ([]byte, error) {
+	data, err := client.SubtitleFile(ctx, k.URL)
+	if err != nil || !keepRaw {
+		return data, err
+	}
+	return data, saveRaw(episodeID, k, filename, data, publishTime)
+}
+
+func saveRaw(episodeID string, k bilibili.SubtitleTrack, filename string, data []byte, publishTime time.Time) error {
+	name := filepath.Join(output, filepath.Dir(filename), rawDir, filepath.Base(filename))
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+
+	if err := utils.WriteFile(name+filepath.Ext(strings.Split(k.URL, "?")[0]), data, publishTime); err != nil {
+		return err
+	}
+
+	meta, err := json.MarshalIndent(rawTrack{EpisodeID: episodeID, Track: k}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFile(name+rawMetaExt, meta, publishTime)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/utils"
+	"golang.org/x/exp/slices"
+)
+
+// checkpointName is the file in the output directory that records the
+// progress of a dl run for --resume.
+const checkpointName = ".bilisubdl-checkpoint.json"
+
+// checkpoint records the episodes a dl run has finished, so --resume can
+// skip them after a crash. It is rewritten after every episode and removed
+// once the run succeeds. The zero value of *checkpoint records nothing.
+type checkpoint struct {
+	name string
+	done map[string]bool
+
+	Args []string `json:"args"`
+	Done []string `json:"done"`
+}
+
+var ckpt *checkpoint
+
+// openCheckpoint returns the checkpoint in dir for a run with args. With
+// resume the episodes recorded by an earlier run with the same args are
+// taken over, otherwise the run starts over.
+func openCheckpoint(dir string, args []string, resume bool) (*checkpoint, error) {
+	c := &checkpoint{name: filepath.Join(dir, checkpointName), done: make(map[string]bool), Args: args}
+	if !resume {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(c.name)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var prev checkpoint
+	if err := json.Unmarshal(data, &prev); err != nil {
+		return nil, fmt.Errorf("[checkpoint: %s] %w", c.name, err)
+	}
+	if !slices.Equal(prev.Args, args) {
+		return nil, fmt.Errorf("[checkpoint: %s] written by another command (%q), run it without --resume to start over", c.name, prev.Args)
+	}
+
+	c.Done = prev.Done
+	for _, k := range c.Done {
+		c.done[k] = true
+	}
+	return c, nil
+}
+
+// checkpointKey identifies j together with the languages selected for it,
+// so batch lines that download the same season in other languages are
+// recorded apart.
+func checkpointKey(j dlJob) string {
+	return j.episodeID + ":" + j.filename + ":" + langSelection()
+}
+
+// langSelection describes the languages downloaded for every episode by
+// --language, --language-fallback or --dual.
+func langSelection() string {
+	switch {
+	case len(dualLanguages) > 0:
+		return strings.Join(dualLanguages, "+")
+	case len(languageFallback) > 0:
+		return strings.Join(languageFallback, ">")
+	}
+	return strings.Join(languages, ",")
+}
+
+// isDone reports whether j was finished by an earlier run.
+func (c *checkpoint) isDone(j dlJob) bool {
+	return c != nil && c.done[checkpointKey(j)]
+}
+
+// markDone records that j is finished.
+func (c *checkpoint) markDone(j dlJob) error {
+	if c == nil || c.isDone(j) {
+		return nil
+	}
+
+	c.done[checkpointKey(j)] = true
+	c.Done = append(c.Done, checkpointKey(j))
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.name), 0o700); err != nil {
+		return err
+	}
+	return utils.WriteFile(c.name, data, time.Now())
+}
+
+// remove deletes the checkpoint after a successful run.
+func (c *checkpoint) remove() error {
+	if c == nil {
+		return nil
+	}
+	if err := os.Remove(c.name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// resumeArgs returns the command line that identifies a run, without
+// --resume.
+func resumeArgs() []string {
+	args := make([]string, 0, len(os.Args))
+	for _, a := range os.Args[1:] {
+		if a != "--resume" && a != "--resume=true" {
+			args = append(args, a)
+		}
+	}
+	return args
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import "testing"
+
+func TestCheckpoint(t *testing.T) {
+	defer func(l []string) { languages = l }(languages)
+	languages = []string{"en"}
+	dir := t.TempDir()
+	args := []string{"dl", "1049041", "-l", "en"}
+	done := dlJob{episodeID: "101", filename: "Show/E1"}
+	left := dlJob{episodeID: "102", filename: "Show/E2"}
+
+	c, err := openCheckpoint(dir, args, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.markDone(done); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = openCheckpoint(dir, args, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.isDone(done) || c.isDone(left) {
+		t.Errorf("done = %v, want only %s", c.Done, checkpointKey(done))
+	}
+
+	languages = []string{"th"}
+	if c.isDone(done) {
+		t.Errorf("done = %v, want %s in another language not done", c.Done, done.episodeID)
+	}
+	languages = []string{"en"}
+
+	if _, err := openCheckpoint(dir, []string{"dl", "37738", "-l", "en"}, true); err == nil {
+		t.Error("openCheckpoint() for another command succeeded, want error")
+	}
+
+	if c, err = openCheckpoint(dir, args, false); err != nil || c.isDone(done) {
+		t.Errorf("openCheckpoint() without resume = %v, %v, want a new checkpoint", c.Done, err)
+	}
+
+	if err := c.remove(); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = openCheckpoint(dir, args, true); err != nil || len(c.Done) != 0 {
+		t.Errorf("openCheckpoint() after remove = %v, %v, want a new checkpoint", c.Done, err)
+	}
+}
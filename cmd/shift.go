@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
+	"github.com/K0ng2/bilisubdl/utils"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var shiftCmd = &cobra.Command{
+	Use:   "shift [FILE]... [flags]",
+	Short: "command corrects the timing of subtitle files that are already downloaded.",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if timing().IsZero() {
+			return fmt.Errorf("nothing to do, set --shift or --fps-from and --fps-to")
+		}
+		cmd.SilenceUsage = true
+
+		for _, s := range args {
+			if err := runShift(s); err != nil {
+				return fmt.Errorf("[file: %s] %w", s, err)
+			}
+		}
+		return nil
+	},
+	Example: "bilisubdl shift \"Show/Episode 1.en.srt\" --shift +1.5s\nbilisubdl shift Show/*.ass --fps-from 25 --fps-to 23.976",
+}
+
+// timing returns the correction set by --shift, --fps-from and --fps-to.
+func timing() subtitle.Timing {
+	return subtitle.Timing{Shift: shiftBy, FromFPS: fpsFrom, ToFPS: fpsTo}
+}
+
+// runShift corrects the timing of filename in place, keeping its mtime.
+func runShift(filename string) error {
+	f, err := subtitle.ParseFormat(filepath.Ext(filename))
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	sub, err := subtitle.RetimeFile(data, f, timing())
+	if err != nil {
+		return err
+	}
+
+	if err := utils.WriteFile(filename, sub, info.ModTime()); err != nil {
+		return err
+	}
+
+	color.Green("* %s", filename)
+	return nil
+}
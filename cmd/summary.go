@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Exit codes returned by Execute.
+const (
+	ExitOK          = 0
+	ExitError       = 1
+	ExitMissing     = 2
+	ExitInterrupted = 130
+)
+
+// errMissing is returned by dl when a requested episode produced no file.
+var errMissing = errors.New("no subtitle found")
+
+type dlStatus int
+
+const (
+	statusDownloaded dlStatus = iota
+	statusUpdated
+	statusExisted
+	statusArchived
+	statusSkippedMachine
+	statusMissing
+	statusFailed
+	statusCount
+)
+
+var statusNames = [statusCount]string{"downloaded", "updated", "existed", "archived", "skipped machine", "missing", "failed"}
+
+// dlSummary counts the outcome of every subtitle handled in a dl run. It is
+// safe for concurrent use.
+type dlSummary struct {
+	mu     sync.Mutex
+	counts [statusCount]int
+}
+
+var summary dlSummary
+
+func (s *dlSummary) add(status dlStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[status]++
+}
+
+func (s *dlSummary) count(status dlStatus) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[status]
+}
+
+func (s *dlSummary) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts = [statusCount]int{}
+}
+
+func (s *dlSummary) render() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := make([]string, 0, statusCount)
+	for _, n := range s.counts {
+		row = append(row, strconv.Itoa(n))
+	}
+
+	fmt.Println()
+	table := newTable(statusNames[:])
+	table.Append(row)
+	table.Render()
+}
+
+// Execute runs RootCmd and returns the exit code for the process: ExitMissing
+// when dl finished but some requested subtitles were not found,
+// ExitInterrupted when a signal stopped dl, ExitError for any other error.
+func Execute() int {
+	err := RootCmd.ExecuteContext(context.Background())
+	cancelTimeout()
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, errMissing):
+		return ExitMissing
+	case errors.Is(err, errInterrupted):
+		return ExitInterrupted
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+	"github.com/K0ng2/bilisubdl/utils"
+)
+
+// nameFields are the values of an episode an --output-template is filled
+// with. The language fields come from the subtitle track.
+type nameFields struct {
+	SeasonTitle    string
+	SeasonID       string
+	SectionTitle   string
+	SectionIndex   int
+	EpisodeNumber  int
+	SeasonNumber   int
+	SeasonEpisode  int
+	AbsoluteNumber int
+	EpisodeID      string
+	ShortTitle     string
+	LongTitle      string
+	PublishTime    time.Time
+}
+
+// templateField returns the value of a field: a string, an int or a
+// time.Time.
+type templateField func(f nameFields, k bilibili.SubtitleTrack) any
+
+var templateFields = map[string]templateField{
+	"season_title":    func(f nameFields, _ bilibili.SubtitleTrack) any { return f.SeasonTitle },
+	"season_id":       func(f nameFields, _ bilibili.SubtitleTrack) any { return f.SeasonID },
+	"section_title":   func(f nameFields, _ bilibili.SubtitleTrack) any { return f.SectionTitle },
+	"section_index":   func(f nameFields, _ bilibili.SubtitleTrack) any { return f.SectionIndex },
+	"episode_number":  func(f nameFields, _ bilibili.SubtitleTrack) any { return f.EpisodeNumber },
+	"episode_id":      func(f nameFields, _ bilibili.SubtitleTrack) any { return f.EpisodeID },
+	"short_title":     func(f nameFields, _ bilibili.SubtitleTrack) any { return f.ShortTitle },
+	"long_title":      func(f nameFields, _ bilibili.SubtitleTrack) any { return f.LongTitle },
+	"season_number":   func(f nameFields, _ bilibili.SubtitleTrack) any { return f.SeasonNumber },
+	"season_episode":  func(f nameFields, _ bilibili.SubtitleTrack) any { return f.SeasonEpisode },
+	"absolute_number": func(f nameFields, _ bilibili.SubtitleTrack) any { return f.AbsoluteNumber },
+	"publish_date":    func(f nameFields, _ bilibili.SubtitleTrack) any { return f.PublishTime },
+	"lang":            func(_ nameFields, k bilibili.SubtitleTrack) any { return k.Key },
+	"lang_title":      func(_ nameFields, k bilibili.SubtitleTrack) any { return k.Title },
+	"lang_flags":      func(_ nameFields, k bilibili.SubtitleTrack) any { return langFlags(k) },
+	"is_machine": func(_ nameFields, k bilibili.SubtitleTrack) any {
+		if k.IsMachine {
+			return "machine"
+		}
+		return ""
+	},
+}
+
+var (
+	intSpec    = regexp.MustCompile(`^[-+ 0]*[0-9]*d$`)
+	stringSpec = regexp.MustCompile(`^-?[0-9]*(\.[0-9]+)?s$`)
+)
+
+// dateSpec maps the strftime directives accepted by {publish_date:...} to
+// Go layouts.
+var dateSpec = strings.NewReplacer(
+	"%Y", "2006",
+	"%y", "06",
+	"%m", "01",
+	"%d", "02",
+	"%H", "15",
+	"%M", "04",
+	"%S", "05",
+	"%%", "%",
+)
+
+const defaultDateSpec = "%Y-%m-%d"
+
+// outputTemplate is a parsed --output-template: literal text with fields in
+// braces, such as "{season_title}/S{section_index:02d}E{episode_number:02d}".
+// A field may carry a directive after a colon: fmt verbs ending in d for
+// numbers, ending in s for text (e.g. ".20s" to cut it), strftime directives
+// for publish_date. "{{" and "}}" are literal braces.
+type outputTemplate struct {
+	parts []templatePart
+}
+
+type templatePart struct {
+	text  string
+	field string
+	spec  string
+}
+
+// parseTemplate parses s and checks its fields and directives.
+func parseTemplate(s string) (*outputTemplate, error) {
+	t := &outputTemplate{}
+	var text strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			text.WriteByte(s[i])
+			i++
+		case s[i] == '}':
+			return nil, fmt.Errorf("unexpected } at position %d in output template", i+1)
+		case s[i] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed { at position %d in output template", i+1)
+			}
+
+			field, spec, _ := strings.Cut(s[i+1:i+end], ":")
+			if err := checkField(field, spec); err != nil {
+				return nil, err
+			}
+
+			if text.Len() > 0 {
+				t.parts = append(t.parts, templatePart{text: text.String()})
+				text.Reset()
+			}
+			t.parts = append(t.parts, templatePart{field: field, spec: spec})
+			i += end
+		default:
+			text.WriteByte(s[i])
+		}
+	}
+
+	if text.Len() > 0 {
+		t.parts = append(t.parts, templatePart{text: text.String()})
+	}
+	return t, nil
+}
+
+func checkField(field, spec string) error {
+	value, ok := templateFields[field]
+	if !ok {
+		return fmt.Errorf("unknown field {%s} in output template", field)
+	}
+	if spec == "" {
+		return nil
+	}
+
+	switch value(nameFields{}, bilibili.SubtitleTrack{}).(type) {
+	case int:
+		ok = intSpec.MatchString(spec)
+	case string:
+		ok = stringSpec.MatchString(spec)
+	case time.Time:
+		ok = strings.Contains(spec, "%")
+	}
+	if !ok {
+		return fmt.Errorf("invalid directive %q for field {%s} in output template", spec, field)
+	}
+	return nil
+}
+
+// has reports whether the template uses one of fields.
+func (t *outputTemplate) has(fields ...string) bool {
+	for _, p := range t.parts {
+		for _, f := range fields {
+			if p.field == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expand fills the template for an episode and a subtitle track. Every field
+// is cleaned like the default filenames, so only the literal text of the
+// template can create folders.
+func (t *outputTemplate) expand(f nameFields, k bilibili.SubtitleTrack) string {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.field == "" {
+			b.WriteString(p.text)
+			continue
+		}
+
+		switch v := templateFields[p.field](f, k).(type) {
+		case int:
+			if p.spec == "" {
+				b.WriteString(strconv.Itoa(v))
+			} else {
+				fmt.Fprintf(&b, "%"+p.spec, v)
+			}
+		case string:
+			if p.spec == "" {
+				b.WriteString(utils.CleanText(v))
+			} else {
+				fmt.Fprintf(&b, "%"+p.spec, utils.CleanText(v))
+			}
+		case time.Time:
+			spec := p.spec
+			if spec == "" {
+				spec = defaultDateSpec
+			}
+			b.WriteString(utils.CleanText(v.Format(dateSpec.Replace(spec))))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/pkg/bilibili"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	fields := nameFields{
+		SeasonTitle:   "Show: Name",
+		SeasonID:      "1049041",
+		SectionTitle:  "Episodes",
+		SectionIndex:  1,
+		EpisodeNumber: 5,
+		EpisodeID:     "2075361",
+		ShortTitle:    "E5",
+		LongTitle:     "The Promise",
+		PublishTime:   time.Date(2023, 1, 29, 18, 30, 0, 0, time.UTC),
+	}
+	track := bilibili.SubtitleTrack{Key: "th", Title: "Thai", IsMachine: true}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{
+			template: "{season_title}/S{section_index:02d}E{episode_number:02d}.{lang}",
+			want:     "Show_ Name/S01E05.th",
+		},
+		{
+			template: "{season_id}-{episode_id} {short_title} {long_title:.3s} [{lang_title}, {is_machine}]",
+			want:     "1049041-2075361 E5 The [Thai, machine]",
+		},
+		{
+			template: "{publish_date} {publish_date:%Y%m%d-%H%M}",
+			want:     "2023-01-29 20230129-1830",
+		},
+		{
+			template: "{{{section_title}}} {episode_number:3d}",
+			want:     "{Episodes}   5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := parseTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tmpl.expand(fields, track); got != tt.want {
+				t.Errorf("expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputTemplateError(t *testing.T) {
+	for _, s := range []string{
+		"{title}",
+		"{season_title",
+		"season_title}",
+		"{episode_number:s}",
+		"{long_title:02d}",
+		"{publish_date:Y}",
+	} {
+		if _, err := parseTemplate(s); err == nil {
+			t.Errorf("parseTemplate(%q) succeeded, want error", s)
+		}
+	}
+}
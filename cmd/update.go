@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
+	"github.com/K0ng2/bilisubdl/utils"
+	"github.com/fatih/color"
+)
+
+// prevExt is appended to the file replaced by --update.
+const prevExt = ".prev"
+
+// updateSub downloads again a subtitle written by an earlier run and
+// replaces the file when it changed upstream, keeping the old file with
+// prevExt. The upstream payload is compared with its hash in the archive, so
+// a new --format, --shift or style does not count as a change. Without that
+// hash the rendered file is compared with the archive or the file itself,
+// so local edits of a subtitle that did not change upstream are kept.
+func updateSub(w io.Writer, entry archiveEntry, filename string, publishTime time.Time, fetch fetchFunc) error {
+	outFile := filepath.Join(output, filename)
+
+	sub, source, err := fetch()
+	if err != nil {
+		return err
+	}
+	hash, sourceHash := contentHash(sub), contentHash(source)
+
+	prev, err := os.ReadFile(outFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var recorded archiveEntry
+	if archive != nil {
+		recorded = archive.get(entry.SubtitleID)
+	}
+
+	var upToDate bool
+	switch {
+	case recorded.SourceHash != "":
+		upToDate = sourceHash == recorded.SourceHash
+	case recorded.Hash != "":
+		upToDate = hash == recorded.Hash
+	case prev != nil:
+		upToDate = hash == contentHash(prev)
+	}
+
+	if upToDate {
+		if archive != nil && recorded.SourceHash == "" {
+			if recorded.SubtitleID == "" {
+				recorded = entry
+				recorded.Path, recorded.Hash, recorded.Time = outFile, hash, time.Now()
+			}
+			recorded.SourceHash = sourceHash
+			if err := archive.add(recorded, true); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(w, color.HiBlackString("# %s", filename), color.HiYellowString("up to date"))
+		summary.add(statusExisted)
+		return nil
+	}
+
+	if prev != nil {
+		if err := os.Rename(outFile, outFile+prevExt); err != nil {
+			return err
+		}
+	} else if err := os.MkdirAll(filepath.Dir(outFile), 0o700); err != nil {
+		return err
+	}
+
+	if err := utils.WriteFile(outFile, sub, publishTime); err != nil {
+		return err
+	}
+
+	if archive != nil {
+		entry.Path, entry.Hash, entry.SourceHash, entry.Time = outFile, hash, sourceHash, time.Now()
+		if err := archive.add(entry, true); err != nil {
+			return err
+		}
+	}
+
+	summary.add(statusUpdated)
+	if !quiet {
+		fmt.Fprintln(w, color.CyanString("~ %s", filename), color.HiCyanString("updated%s", diffNote(filename, prev, sub)))
+	}
+	return nil
+}
+
+// diffNote summarizes how the cues of the subtitle file filename changed
+// from prev to next, or returns "" when they cannot be compared.
+func diffNote(filename string, prev, next []byte) string {
+	f, err := subtitle.ParseFormat(filepath.Ext(filename))
+	if err != nil || prev == nil {
+		return ""
+	}
+
+	a, err := subtitle.Unmarshal(prev, f)
+	if err != nil {
+		return ""
+	}
+	b, err := subtitle.Unmarshal(next, f)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", subtitle.Diff(a, b))
+}
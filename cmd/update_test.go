@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateSubSourceHash(t *testing.T) {
+	defer func(o string, a *downloadArchive, q bool) { output, archive, quiet = o, a, q }(output, archive, quiet)
+	output, quiet = t.TempDir(), true
+
+	var err error
+	if archive, err = openArchive(filepath.Join(output, "archive")); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(output, "E1.en.srt")
+	if err := os.WriteFile(name, []byte("old render"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entry := archiveEntry{SubtitleID: "1011", Hash: contentHash([]byte("old render")), SourceHash: contentHash([]byte("upstream"))}
+	if err := archive.add(entry, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		wantFile string
+	}{
+		{name: "rendering changed", source: "upstream", wantFile: "old render"},
+		{name: "upstream changed", source: "upstream v2", wantFile: "new render"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetch := func() ([]byte, []byte, error) { return []byte("new render"), []byte(tt.source), nil }
+			if err := updateSub(io.Discard, archiveEntry{SubtitleID: "1011"}, "E1.en.srt", time.Now(), fetch); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(name); string(got) != tt.wantFile {
+				t.Errorf("file = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+
+	if got := archive.get("1011").SourceHash; got != contentHash([]byte("upstream v2")) {
+		t.Errorf("source hash = %s, want the hash of the new upstream payload", got)
+	}
+}
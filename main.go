@@ -1,6 +1,8 @@
 package main
 
 import (
+	"os"
+
 	"github.com/K0ng2/bilisubdl/cmd"
 )
 
@@ -8,5 +10,5 @@ var version string
 
 func main() {
 	cmd.RootCmd.Version = version
-	cmd.RootCmd.Execute()
+	os.Exit(cmd.Execute())
 }
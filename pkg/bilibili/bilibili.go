@@ -1,10 +1,14 @@
 package bilibili
 
 import (
-	"fmt"
+	"context"
 	"io"
+	"net/http"
+	"path"
 	"strings"
+	"time"
 
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
 	"github.com/K0ng2/bilisubdl/utils"
 	"golang.org/x/exp/slices"
 )
@@ -23,62 +27,195 @@ https://api.bilibili.tv/intl/gateway/web/v2/subtitle?s_locale&episode_id=368729
 
 const (
 	BilibiliAPI            string = "https://api.bilibili.tv/intl/gateway"
-	BilibiliInfoAPI        string = BilibiliAPI + "/web/v2/ogv/play/"
-	BilibiliSeasonInfoAPI  string = BilibiliInfoAPI + "season_info"
-	BilibiliEpisodeInfoAPI string = BilibiliInfoAPI + "episodes"
-	BilibiliSubtitleAPI    string = BilibiliAPI + "/m/subtitle"
-	BilibiliTimelineAPI    string = BilibiliAPI + "/web/v2/home/timeline"
-	BilibiliSearchAPI      string = BilibiliAPI + "/web/v2/search_v2/anime"
+	BilibiliInfoAPI        string = BilibiliAPI + infoPath
+	BilibiliSeasonInfoAPI  string = BilibiliAPI + seasonInfoPath
+	BilibiliEpisodeInfoAPI string = BilibiliAPI + episodeInfoPath
+	BilibiliSubtitleAPI    string = BilibiliAPI + subtitlePath
+	BilibiliTimelineAPI    string = BilibiliAPI + timelinePath
+	BilibiliSearchAPI      string = BilibiliAPI + searchPath
 	// BilibiliSubtitleAPI string = bilibiliAPI + "/subtitle?s_locale&episode_id="
+
+	DefaultLocale    string = "en_US"
+	DefaultUserAgent string = "bilisubdl (+https://github.com/K0ng2/bilisubdl)"
+	DefaultRetries   int    = 3
+
+	DefaultRetryWait = time.Second
+)
+
+const (
+	infoPath        = "/web/v2/ogv/play/"
+	seasonInfoPath  = infoPath + "season_info"
+	episodeInfoPath = infoPath + "episodes"
+	subtitlePath    = "/m/subtitle"
+	timelinePath    = "/web/v2/home/timeline"
+	searchPath      = "/web/v2/search_v2/anime"
 )
 
-func GetApi[S Info | Episodes | Episode | EpisodeFile | Timeline | Search](s *S, url string, query map[string]string) (*S, error) {
-	resp, err := utils.Request(url, query)
+// Client talks to the bilibili.tv API. The zero value is not usable; create
+// one with NewClient and adjust its fields before the first request.
+type Client struct {
+	// BaseURL is the API gateway, e.g. BilibiliAPI or a local mirror.
+	BaseURL string
+	// HTTPClient is used for every request, including subtitle files.
+	HTTPClient *http.Client
+	// Locale is sent as s_locale unless a request sets it explicitly.
+	Locale string
+	// UserAgent is sent with every request when not empty.
+	UserAgent string
+	// Retries is how many times a request that failed with a transient
+	// error is sent again.
+	Retries int
+	// RetryWait is the initial backoff between retries.
+	RetryWait time.Duration
+	// Limiter paces all requests made by the client, API calls and
+	// subtitle files alike. A nil Limiter does not limit.
+	Limiter *utils.RateLimiter
+}
+
+// DefaultClient is used by GetApi and GetSubtitle.
+var DefaultClient = NewClient()
+
+// NewClient returns a Client for the public bilibili.tv API.
+func NewClient() *Client {
+	return &Client{
+		BaseURL: BilibiliAPI,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		Locale:    DefaultLocale,
+		UserAgent: DefaultUserAgent,
+		Retries:   DefaultRetries,
+		RetryWait: DefaultRetryWait,
+	}
+}
+
+// SeasonInfo returns the season information for seasonID.
+func (c *Client) SeasonInfo(ctx context.Context, seasonID string) (*Info, error) {
+	return getApi(ctx, c, new(Info), c.BaseURL+seasonInfoPath, map[string]string{"season_id": seasonID})
+}
+
+// Episodes returns the sections and episodes of seasonID.
+func (c *Client) Episodes(ctx context.Context, seasonID string) (*Episodes, error) {
+	return getApi(ctx, c, new(Episodes), c.BaseURL+episodeInfoPath, map[string]string{"season_id": seasonID})
+}
+
+// Subtitles returns the subtitle tracks available for episodeID.
+func (c *Client) Subtitles(ctx context.Context, episodeID string) (*EpisodeFile, error) {
+	return getApi(ctx, c, new(EpisodeFile), c.BaseURL+subtitlePath, map[string]string{"ep_id": episodeID})
+}
+
+// Timeline returns the weekly release timeline.
+func (c *Client) Timeline(ctx context.Context) (*Timeline, error) {
+	return getApi(ctx, c, new(Timeline), c.BaseURL+timelinePath, nil)
+}
+
+// Search returns the first page of anime matching keyword.
+func (c *Client) Search(ctx context.Context, keyword string) (*Search, error) {
+	query := map[string]string{
+		"keyword":  keyword,
+		"platform": "web",
+		"pn":       "1",
+		"ps":       "20",
+	}
+	return getApi(ctx, c, new(Search), c.BaseURL+searchPath, query)
+}
+
+// SubtitleFile downloads the subtitle file at url as is.
+func (c *Client) SubtitleFile(ctx context.Context, url string) ([]byte, error) {
+	resp, err := c.request(ctx, url, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Close()
 
-	if err = utils.JsonUnmarshal(resp, s); err != nil {
+	return io.ReadAll(resp)
+}
+
+// Subtitle downloads the subtitle file at url and decodes it according to
+// its extension.
+func (c *Client) Subtitle(ctx context.Context, url string) (*subtitle.Subtitle, error) {
+	f, err := SubtitleFormat(url)
+	if err != nil {
 		return nil, err
 	}
 
-	return s, nil
+	body, err := c.SubtitleFile(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return subtitle.Unmarshal(body, f)
 }
 
-func GetSubtitle(url, fileType string) ([]byte, error) {
-	resp, err := utils.Request(url, nil)
+// SubtitleFormat returns the format of the subtitle file at url, as told by
+// its extension.
+func SubtitleFormat(url string) (subtitle.Format, error) {
+	return subtitle.ParseFormat(path.Ext(strings.Split(url, "?")[0]))
+}
+
+func (c *Client) request(ctx context.Context, url string, query map[string]string) (io.ReadCloser, error) {
+	opts := &utils.RequestOptions{
+		Retries:   c.Retries,
+		RetryWait: c.RetryWait,
+		Limiter:   c.Limiter,
+	}
+	if c.UserAgent != "" {
+		opts.Header = map[string]string{"User-Agent": c.UserAgent}
+	}
+	return utils.Request(ctx, c.HTTPClient, url, query, opts)
+}
+
+func getApi[S Info | Episodes | Episode | EpisodeFile | Timeline | Search](ctx context.Context, c *Client, s *S, url string, query map[string]string) (*S, error) {
+	q := make(map[string]string, len(query)+1)
+	for j, s := range query {
+		q[j] = s
+	}
+	if _, ok := q["s_locale"]; !ok && c.Locale != "" {
+		q["s_locale"] = c.Locale
+	}
+
+	resp, err := c.request(ctx, url, q)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Close()
 
-	switch fileType {
-	case ".srt":
-		subJson := new(Subtitle)
-		if err := utils.JsonUnmarshal(resp, subJson); err != nil {
-			return nil, err
-		}
+	if err = utils.JsonUnmarshal(resp, s); err != nil {
+		return nil, err
+	}
 
-		return []byte(subJson.toSRT()), nil
-	default:
-		body, err := io.ReadAll(resp)
-		if err != nil {
-			return nil, err
+	if r, ok := any(s).(response); ok {
+		if code, message := r.status(); code != 0 {
+			return nil, &APIError{Code: code, Message: message, Endpoint: strings.TrimPrefix(url, c.BaseURL)}
 		}
-		return body, nil
 	}
+
+	return s, nil
 }
 
-func (subJson *Subtitle) toSRT() string {
-	sub := make([]string, 0, len(subJson.Body))
-	for i, s := range subJson.Body {
-		content := s.Content
-		if s.Location != 2 {
-			content = fmt.Sprintf("{\\an%d}%s", s.Location, content)
-		}
-		sub = append(sub, fmt.Sprintf("%d\n%s --> %s\n%s", i+1, utils.SecondToTime(s.From), utils.SecondToTime(s.To), content))
+// GetApi requests url with DefaultClient and decodes the response into s.
+func GetApi[S Info | Episodes | Episode | EpisodeFile | Timeline | Search](ctx context.Context, s *S, url string, query map[string]string) (*S, error) {
+	return getApi(ctx, DefaultClient, s, url, query)
+}
+
+// GetSubtitle downloads a subtitle file with DefaultClient and converts it
+// to fileType (e.g. ".srt"). Files in an unknown format are returned as is.
+func GetSubtitle(ctx context.Context, url, fileType string) ([]byte, error) {
+	body, err := DefaultClient.SubtitleFile(ctx, url)
+	if err != nil {
+		return nil, err
 	}
-	return strings.Join(sub, "\n\n") + "\n"
+
+	from, err := SubtitleFormat(url)
+	if err != nil {
+		return body, nil
+	}
+
+	to, err := subtitle.ParseFormat(fileType)
+	if err != nil || from == to {
+		return body, nil
+	}
+
+	return subtitle.Convert(body, from, to)
 }
 
 // func ExtractSel[E Section | Episode](e []E, sel []string) []E {
@@ -0,0 +1,163 @@
+package bilibili
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/K0ng2/bilisubdl/pkg/subtitle"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	c := NewClient()
+	c.BaseURL = ts.URL
+	c.HTTPClient = ts.Client()
+	return c
+}
+
+func TestClientSeasonInfo(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != seasonInfoPath {
+			t.Errorf("path = %s, want %s", r.URL.Path, seasonInfoPath)
+		}
+		if got := r.URL.Query().Get("season_id"); got != "1049041" {
+			t.Errorf("season_id = %s, want 1049041", got)
+		}
+		if got := r.URL.Query().Get("s_locale"); got != "th_TH" {
+			t.Errorf("s_locale = %s, want th_TH", got)
+		}
+		if got := r.UserAgent(); got != "test-agent" {
+			t.Errorf("User-Agent = %s, want test-agent", got)
+		}
+		fmt.Fprint(w, `{"code":0,"message":"0","data":{"season":{"title":"Test Season"}}}`)
+	})
+	c.Locale = "th_TH"
+	c.UserAgent = "test-agent"
+
+	info, err := c.SeasonInfo(context.Background(), "1049041")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Data.Season.Title != "Test Season" {
+		t.Errorf("title = %q, want %q", info.Data.Season.Title, "Test Season")
+	}
+}
+
+func TestClientSubtitle(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"body":[{"from":1.5,"to":3,"location":2,"content":"Hello"},{"from":61,"to":62.25,"location":8,"content":"Top"}]}`)
+	})
+
+	sub, err := c.Subtitle(context.Background(), c.BaseURL+"/sub.json?auth_key=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []subtitle.Cue{
+		{Start: 1500 * time.Millisecond, End: 3 * time.Second, Position: 2, Text: "Hello"},
+		{Start: 61 * time.Second, End: 62250 * time.Millisecond, Position: 8, Text: "Top"},
+	}
+	if !reflect.DeepEqual(sub.Cues, want) {
+		t.Errorf("Subtitle() = %+v, want %+v", sub.Cues, want)
+	}
+}
+
+func TestClientAPIError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{
+			name: "not found",
+			body: `{"code":-404,"message":"not found"}`,
+			want: ErrNotFound,
+		},
+		{
+			name: "region blocked",
+			body: `{"code":-10403,"message":"area limit"}`,
+			want: ErrRegionBlocked,
+		},
+		{
+			name: "rate limited",
+			body: `{"code":-412,"message":"request was banned"}`,
+			want: ErrRateLimited,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			})
+
+			_, err := c.Episodes(context.Background(), "1")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Episodes() error = %v, want %v", err, tt.want)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Endpoint != episodeInfoPath {
+				t.Errorf("Episodes() error = %#v, want APIError for %s", err, episodeInfoPath)
+			}
+		})
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Ref
+	}{
+		{"1049041", Ref{SeasonID: "1049041"}},
+		{"https://www.bilibili.tv/en/play/1049041", Ref{SeasonID: "1049041"}},
+		{"https://www.bilibili.tv/en/play/1049041/2075361", Ref{SeasonID: "1049041", EpisodeID: "2075361"}},
+		{"https://www.bilibili.tv/play/1049041/2075361?bstar_from=bstar-web.pgc-video-detail.episode.0", Ref{SeasonID: "1049041", EpisodeID: "2075361"}},
+		{"https://m.bilibili.tv/th/play/1049041/2075361", Ref{SeasonID: "1049041", EpisodeID: "2075361"}},
+		{"bilibili.tv/en/media/1049041", Ref{SeasonID: "1049041"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRef(tt.in)
+		if err != nil {
+			t.Errorf("ParseRef(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{
+		"https://www.bilibili.tv/en/video/2042186830",
+		"https://example.com/en/play/1049041",
+		"abc",
+	} {
+		if _, err := ParseRef(in); !errors.Is(err, ErrUnsupportedURL) {
+			t.Errorf("ParseRef(%q) error = %v, want %v", in, err, ErrUnsupportedURL)
+		}
+	}
+}
+
+func TestClientResolveRef(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://www.bilibili.tv/en/play/1049041/2075361", http.StatusFound)
+	})
+	defer func(hosts []string) { shortLinkHosts = hosts }(shortLinkHosts)
+	shortLinkHosts = []string{"127.0.0.1"}
+
+	got, err := c.ResolveRef(context.Background(), c.BaseURL+"/abcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Ref{SeasonID: "1049041", EpisodeID: "2075361"}); got != want {
+		t.Errorf("ResolveRef() = %+v, want %+v", got, want)
+	}
+}
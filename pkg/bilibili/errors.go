@@ -0,0 +1,53 @@
+package bilibili
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors matched by APIError through errors.Is.
+var (
+	ErrNotFound      = errors.New("bilibili: not found")
+	ErrRegionBlocked = errors.New("bilibili: not available in your region")
+	ErrRateLimited   = errors.New("bilibili: rate limited")
+)
+
+// errorCodes maps well-known API codes to their sentinel errors.
+var errorCodes = map[int]error{
+	-404:   ErrNotFound,
+	-10403: ErrRegionBlocked,
+	-412:   ErrRateLimited,
+	-509:   ErrRateLimited,
+	-799:   ErrRateLimited,
+}
+
+// APIError is returned when the API answers with a non-zero code.
+type APIError struct {
+	Code     int
+	Message  string
+	Endpoint string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("bilibili: %s: code %d", e.Endpoint, e.Code)
+	}
+	return fmt.Sprintf("bilibili: %s: %s (code %d)", e.Endpoint, e.Message, e.Code)
+}
+
+// Is reports whether target is the sentinel error for e.Code.
+func (e *APIError) Is(target error) bool {
+	sentinel, ok := errorCodes[e.Code]
+	return ok && sentinel == target
+}
+
+// response is implemented by every API response carrying a code and message.
+type response interface {
+	status() (int, string)
+}
+
+func (s *Info) status() (int, string)        { return s.Code, s.Message }
+func (s *Episodes) status() (int, string)    { return s.Code, s.Message }
+func (s *EpisodeFile) status() (int, string) { return s.Code, s.Message }
+func (s *Timeline) status() (int, string)    { return s.Code, s.Message }
+func (s *Search) status() (int, string)      { return s.Code, s.Message }
@@ -0,0 +1,157 @@
+package bilibili
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// ErrUnsupportedURL is returned for links that do not point to a season or
+// an episode on bilibili.tv.
+var ErrUnsupportedURL = errors.New("bilibili: unsupported URL")
+
+// shortLinkHosts redirect to bilibili.tv pages.
+var shortLinkHosts = []string{"bili.im", "b23.tv"}
+
+// Ref is a season or an episode of a season, as given by an ID or a
+// bilibili.tv link.
+type Ref struct {
+	SeasonID string
+	// EpisodeID is empty when the whole season is meant.
+	EpisodeID string
+}
+
+// IsEpisode reports whether r points to a single episode.
+func (r Ref) IsEpisode() bool {
+	return r.EpisodeID != ""
+}
+
+// ParseRef parses a season ID or a bilibili.tv link such as
+// https://www.bilibili.tv/en/play/1049041 for a season or
+// https://www.bilibili.tv/en/play/1049041/2075361 for an episode. A bare
+// number is taken as a season ID. Short links have to be resolved with
+// Client.ResolveRef first.
+func ParseRef(s string) (Ref, error) {
+	s = strings.TrimSpace(s)
+	if isNumber(s) {
+		return Ref{SeasonID: s}, nil
+	}
+
+	u, err := parseLink(s)
+	if err != nil {
+		return Ref{}, err
+	}
+
+	if !isBilibiliHost(u.Hostname()) {
+		return Ref{}, fmt.Errorf("%w: %s", ErrUnsupportedURL, s)
+	}
+
+	// /[lang/]play/<season>[/<episode>] or /[lang/]media/<season>
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, p := range parts {
+		if (p != "play" && p != "media") || i+1 >= len(parts) || !isNumber(parts[i+1]) {
+			continue
+		}
+
+		ref := Ref{SeasonID: parts[i+1]}
+		if p == "play" && i+2 < len(parts) && isNumber(parts[i+2]) {
+			ref.EpisodeID = parts[i+2]
+		}
+		return ref, nil
+	}
+	return Ref{}, fmt.Errorf("%w: %s", ErrUnsupportedURL, s)
+}
+
+// ResolveRef is ParseRef that also follows short links (e.g. bili.im) to
+// the page they point to.
+func (c *Client) ResolveRef(ctx context.Context, s string) (Ref, error) {
+	s = strings.TrimSpace(s)
+	if u, err := parseLink(s); err == nil && slices.Contains(shortLinkHosts, strings.ToLower(u.Hostname())) {
+		long, err := c.followLink(ctx, u.String())
+		if err != nil {
+			return Ref{}, err
+		}
+		s = long
+	}
+	return ParseRef(s)
+}
+
+// followLink returns the bilibili.tv URL link redirects to, without
+// loading that page.
+func (c *Client) followLink(ctx context.Context, link string) (string, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	if c.HTTPClient != nil {
+		client = *c.HTTPClient
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if isBilibiliHost(req.URL.Hostname()) {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	if err := c.Limiter.Wait(ctx); err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if loc, err := resp.Location(); err == nil {
+		return loc.String(), nil
+	}
+	return resp.Request.URL.String(), nil
+}
+
+func isBilibiliHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "bilibili.tv" || strings.HasSuffix(host, ".bilibili.tv")
+}
+
+// parseLink parses s as an absolute URL, adding https:// when the scheme is
+// missing (e.g. "www.bilibili.tv/en/play/1049041").
+func parseLink(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedURL, s)
+	}
+	return u, nil
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -41,25 +41,18 @@ type EpisodeFile struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Data    struct {
-		Subtitles []struct {
-			URL string `json:"url"`
-			ID  int    `json:"id"`
-			// Lang    string `json:"lang"`
-			Title string `json:"title"`
-			// LangKey string `json:"lang_key"`
-			Key       string `json:"key"`
-			IsMachine bool   `json:"is_machine"`
-		} `json:"subtitles"`
+		Subtitles []SubtitleTrack `json:"subtitles"`
 	} `json:"data"`
 }
 
-type Subtitle struct {
-	Body []struct {
-		From     float64 `json:"from"`
-		To       float64 `json:"to"`
-		Location int     `json:"location"`
-		Content  string  `json:"content"`
-	} `json:"body"`
+type SubtitleTrack struct {
+	URL string `json:"url"`
+	ID  int    `json:"id"`
+	// Lang    string `json:"lang"`
+	Title string `json:"title"`
+	// LangKey string `json:"lang_key"`
+	Key       string `json:"key"`
+	IsMachine bool   `json:"is_machine"`
 }
 
 type Timeline struct {
@@ -0,0 +1,311 @@
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const assHeader = `[Script Info]
+; Script generated by bilisubdl
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+`
+
+const assEvents = `
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+var (
+	assDefaultFields = []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+	assTags          = strings.NewReplacer(
+		"\n", `\N`,
+		"<i>", `{\i1}`, "</i>", `{\i0}`,
+		"<b>", `{\b1}`, "</b>", `{\b0}`,
+		"<u>", `{\u1}`, "</u>", `{\u0}`,
+	)
+)
+
+func (st Style) format() string {
+	bold := 0
+	if st.Bold {
+		bold = -1
+	}
+	return fmt.Sprintf("Style: %s,%s,%d,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,%d,0,0,0,100,100,0,0,1,%g,%g,%d,%d,%d,%d,1\n",
+		st.Name, st.Font, st.Size, bold, st.Outline, st.Shadow, st.Alignment, st.MarginL, st.MarginR, st.MarginV)
+}
+
+// formatASSTime formats d as an ASS timestamp (h:mm:ss.cc).
+func formatASSTime(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	cs := int64((d + 5*time.Millisecond) / (10 * time.Millisecond))
+	secs, cs := cs/100, cs%100
+	mins, secs := secs/60, secs%60
+	hrs, mins := mins/60, mins%60
+	return fmt.Sprintf("%d:%02d:%02d.%02d", hrs, mins, secs, cs)
+}
+
+// writeASS writes s as an ASS script using s.Styles, or DefaultStyle when
+// it has none. Cues placed anywhere but their style's alignment get an
+// {\anN} override.
+func writeASS(s *Subtitle) []byte {
+	styles := s.Styles
+	if len(styles) == 0 {
+		styles = []Style{DefaultStyle}
+	}
+
+	alignments := make(map[string]int, len(styles))
+	var sb strings.Builder
+	sb.WriteString(assHeader)
+	for _, st := range styles {
+		alignments[st.Name] = st.Alignment
+		sb.WriteString(st.format())
+	}
+
+	sb.WriteString(assEvents)
+	for _, c := range s.Cues {
+		style := c.Style
+		if _, ok := alignments[style]; !ok {
+			style = styles[0].Name
+		}
+
+		content := assTags.Replace(strings.ReplaceAll(c.Text, "\r\n", "\n"))
+		if p := c.position(); p != alignments[style] {
+			content = fmt.Sprintf("{\\an%d}%s", p, content)
+		}
+		fmt.Fprintf(&sb, "Dialogue: 0,%s,%s,%s,,0,0,0,,%s\n", formatASSTime(c.Start), formatASSTime(c.End), style, content)
+	}
+	return []byte(sb.String())
+}
+
+// parseASS flattens the Dialogue events of an ASS or SSA script into cues.
+// Positions come from the event's style or an \an/\a override, italic, bold
+// and underline overrides become <i>, <b> and <u> tags, drawings are dropped
+// and every other override tag is stripped.
+func parseASS(data []byte) (*Subtitle, error) {
+	var (
+		s          = new(Subtitle)
+		section    string
+		fields     = assDefaultFields
+		styleField []string
+		alignments = map[string]int{}
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(line)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(key), strings.TrimSpace(value)
+
+		switch section {
+		case "[v4+ styles]", "[v4 styles]":
+			switch key {
+			case "format":
+				styleField = splitASSFormat(value)
+			case "style":
+				st := parseASSStyle(styleField, strings.Split(value, ","), section == "[v4 styles]")
+				alignments[st.Name] = st.Alignment
+				s.Styles = append(s.Styles, st)
+			}
+		case "[events]":
+			switch key {
+			case "format":
+				fields = splitASSFormat(value)
+			case "dialogue":
+				values := strings.SplitN(value, ",", len(fields))
+				if len(values) < len(fields) {
+					return nil, fmt.Errorf("subtitle: malformed ASS dialogue %q", line)
+				}
+
+				var (
+					c    = Cue{Position: DefaultPosition}
+					text string
+				)
+				for i, f := range fields {
+					var err error
+					switch f {
+					case "start":
+						c.Start, err = parseTime(values[i])
+					case "end":
+						c.End, err = parseTime(values[i])
+					case "style":
+						c.Style = strings.TrimPrefix(strings.TrimSpace(values[i]), "*")
+						if n, ok := alignments[c.Style]; ok && n >= 1 && n <= 9 {
+							c.Position = n
+						}
+					case "text":
+						text = values[i]
+					}
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				if c.Text = parseASSText(text, &c.Position); c.Text != "" {
+					s.Cues = append(s.Cues, c)
+				}
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func splitASSFormat(value string) []string {
+	fields := strings.Split(value, ",")
+	for i := range fields {
+		fields[i] = strings.ToLower(strings.TrimSpace(fields[i]))
+	}
+	return fields
+}
+
+func parseASSStyle(fields, values []string, legacy bool) Style {
+	st := Style{Alignment: DefaultPosition}
+	for i, f := range fields {
+		if i >= len(values) {
+			break
+		}
+		v := strings.TrimSpace(values[i])
+		switch f {
+		case "name":
+			st.Name = v
+		case "fontname":
+			st.Font = v
+		case "fontsize":
+			size, _ := strconv.ParseFloat(v, 64)
+			st.Size = int(size)
+		case "bold":
+			st.Bold = v != "0"
+		case "outline":
+			st.Outline, _ = strconv.ParseFloat(v, 64)
+		case "shadow":
+			st.Shadow, _ = strconv.ParseFloat(v, 64)
+		case "alignment":
+			st.Alignment, _ = strconv.Atoi(v)
+			if legacy {
+				st.Alignment = ssaAlignment(st.Alignment)
+			}
+		case "marginl":
+			st.MarginL, _ = strconv.Atoi(v)
+		case "marginr":
+			st.MarginR, _ = strconv.Atoi(v)
+		case "marginv":
+			st.MarginV, _ = strconv.Atoi(v)
+		}
+	}
+	return st
+}
+
+// ssaAlignment converts a legacy SSA alignment (1-3 bottom, +4 top, +8
+// middle) to the numpad layout used by ASS.
+func ssaAlignment(n int) int {
+	switch {
+	case n >= 9 && n <= 11:
+		return n - 5
+	case n >= 5 && n <= 7:
+		return n + 2
+	default:
+		return n
+	}
+}
+
+// parseASSText converts the text of a Dialogue event. Alignment overrides
+// are stored in position.
+func parseASSText(text string, position *int) string {
+	var (
+		sb      strings.Builder
+		open    = map[string]bool{}
+		order   []string
+		drawing bool
+	)
+
+	setTag := func(tag string, on bool) {
+		if open[tag] == on {
+			return
+		}
+		open[tag] = on
+		if on {
+			order = append(order, tag)
+			sb.WriteString("<" + tag + ">")
+			return
+		}
+		sb.WriteString("</" + tag + ">")
+		for i, t := range order {
+			if t == tag {
+				order = append(order[:i], order[i+1:]...)
+				break
+			}
+		}
+	}
+	closeAll := func() {
+		for i := len(order) - 1; i >= 0; i-- {
+			open[order[i]] = false
+			sb.WriteString("</" + order[i] + ">")
+		}
+		order = nil
+	}
+
+	for len(text) > 0 {
+		start := strings.IndexByte(text, '{')
+		end := strings.IndexByte(text, '}')
+		if start < 0 || end < start {
+			if !drawing {
+				sb.WriteString(text)
+			}
+			break
+		}
+
+		if !drawing {
+			sb.WriteString(text[:start])
+		}
+		for _, tag := range strings.Split(text[start+1:end], `\`) {
+			switch {
+			case tag == "":
+			case strings.HasPrefix(tag, "an"):
+				if n, err := strconv.Atoi(tag[2:]); err == nil && n >= 1 && n <= 9 {
+					*position = n
+				}
+			case tag[0] == 'a' && len(tag) > 1 && tag[1] >= '0' && tag[1] <= '9':
+				if n, err := strconv.Atoi(tag[1:]); err == nil {
+					*position = ssaAlignment(n)
+				}
+			case tag == "i1" || tag == "i0", tag == "u1" || tag == "u0":
+				setTag(tag[:1], tag[1] == '1')
+			case tag[0] == 'b' && len(tag) > 1 && tag[1] >= '0' && tag[1] <= '9':
+				setTag("b", tag != "b0")
+			case tag[0] == 'p' && len(tag) > 1 && tag[1] >= '0' && tag[1] <= '9':
+				drawing = tag != "p0"
+			case tag[0] == 'r':
+				closeAll()
+			}
+		}
+		text = text[end+1:]
+	}
+	closeAll()
+
+	return strings.TrimSpace(strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(sb.String()))
+}
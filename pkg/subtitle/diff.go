@@ -0,0 +1,54 @@
+package subtitle
+
+import "fmt"
+
+// DiffStats counts how the cues of two versions of a subtitle differ.
+type DiffStats struct {
+	Unchanged int
+	Changed   int
+	Added     int
+	Removed   int
+}
+
+func (d DiffStats) String() string {
+	return fmt.Sprintf("%d changed, %d added, %d removed", d.Changed, d.Added, d.Removed)
+}
+
+// Diff compares the cues of prev and next. Cues equal in timing and text
+// are unchanged; a cue of next overlapping one left in prev counts as
+// changed; the others are added to or removed from prev.
+func Diff(prev, next *Subtitle) DiffStats {
+	var d DiffStats
+	left := make([]Cue, 0, len(prev.Cues))
+	exact := make(map[Cue]int, len(prev.Cues))
+	for _, c := range prev.Cues {
+		exact[c]++
+	}
+
+	var rest []Cue
+	for _, c := range next.Cues {
+		if exact[c] > 0 {
+			exact[c]--
+			d.Unchanged++
+			continue
+		}
+		rest = append(rest, c)
+	}
+	for _, c := range prev.Cues {
+		if exact[c] > 0 {
+			exact[c]--
+			left = append(left, c)
+		}
+	}
+
+	for _, c := range rest {
+		if i := bestOverlap(left, c); i >= 0 {
+			left = append(left[:i], left[i+1:]...)
+			d.Changed++
+			continue
+		}
+		d.Added++
+	}
+	d.Removed = len(left)
+	return d
+}
@@ -0,0 +1,30 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	s := time.Second
+	prev := &Subtitle{Cues: []Cue{
+		{Start: 1 * s, End: 2 * s, Position: 2, Text: "Hello"},
+		{Start: 3 * s, End: 4 * s, Position: 2, Text: "Teh typo"},
+		{Start: 5 * s, End: 6 * s, Position: 2, Text: "Gone"},
+		{Start: 7 * s, End: 8 * s, Position: 2, Text: "Same"},
+	}}
+	next := &Subtitle{Cues: []Cue{
+		{Start: 1 * s, End: 2 * s, Position: 2, Text: "Hello"},
+		{Start: 3 * s, End: 4 * s, Position: 2, Text: "The typo"},
+		{Start: 7 * s, End: 8 * s, Position: 2, Text: "Same"},
+		{Start: 9 * s, End: 10 * s, Position: 2, Text: "New"},
+	}}
+
+	want := DiffStats{Unchanged: 2, Changed: 1, Added: 1, Removed: 1}
+	if got := Diff(prev, next); got != want {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+	if got := Diff(prev, prev); got != (DiffStats{Unchanged: 4}) {
+		t.Errorf("Diff() of the same subtitle = %+v", got)
+	}
+}
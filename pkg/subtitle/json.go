@@ -0,0 +1,64 @@
+package subtitle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// bilibiliJSON is the subtitle format served by the bilibili.tv API.
+type bilibiliJSON struct {
+	Body []bilibiliLine `json:"body"`
+}
+
+type bilibiliLine struct {
+	From     float64 `json:"from"`
+	To       float64 `json:"to"`
+	Location int     `json:"location"`
+	Content  string  `json:"content"`
+}
+
+func parseJSON(data []byte) (*Subtitle, error) {
+	// body is a pointer so a file without it, any other JSON object, is told
+	// apart from a subtitle without lines.
+	var sub struct {
+		Body *[]bilibiliLine `json:"body"`
+	}
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, err
+	}
+	if sub.Body == nil {
+		return nil, errors.New("subtitle: not a bilibili subtitle, no body")
+	}
+
+	s := &Subtitle{Cues: make([]Cue, 0, len(*sub.Body))}
+	for _, l := range *sub.Body {
+		s.Cues = append(s.Cues, Cue{
+			Start:    seconds(l.From),
+			End:      seconds(l.To),
+			Position: l.Location,
+			Text:     l.Content,
+		})
+	}
+	return s, nil
+}
+
+func writeJSON(s *Subtitle) ([]byte, error) {
+	sub := bilibiliJSON{Body: make([]bilibiliLine, 0, len(s.Cues))}
+	for _, c := range s.Cues {
+		sub.Body = append(sub.Body, bilibiliLine{
+			From:     c.Start.Seconds(),
+			To:       c.End.Seconds(),
+			Location: c.position(),
+			Content:  c.Text,
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(sub); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+package subtitle
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Merge returns a bilingual subtitle holding the cues of primary with the
+// text of secondary stacked below their own. Every secondary cue joins the
+// primary cue it overlaps the most; secondary cues that overlap none are
+// kept as they are.
+func Merge(primary, secondary *Subtitle) *Subtitle {
+	cues := make([]Cue, len(primary.Cues))
+	copy(cues, primary.Cues)
+
+	extra := make([][]string, len(cues))
+	for _, sc := range secondary.Cues {
+		if i := bestOverlap(cues, sc); i >= 0 {
+			extra[i] = append(extra[i], sc.Text)
+		} else {
+			cues = append(cues, sc)
+			extra = append(extra, nil)
+		}
+	}
+
+	for i := range cues {
+		if len(extra[i]) > 0 {
+			cues[i].Text = strings.Join(append([]string{cues[i].Text}, extra[i]...), "\n")
+		}
+	}
+
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	return &Subtitle{Styles: primary.Styles, Cues: cues}
+}
+
+// MergeStyled returns a bilingual subtitle for ASS that keeps both tracks
+// as separate events: primary in bottom and secondary in top. Cues keep
+// their own position when it differs from the default.
+func MergeStyled(primary, secondary *Subtitle, bottom, top Style) *Subtitle {
+	s := &Subtitle{
+		Styles: []Style{bottom, top},
+		Cues:   make([]Cue, 0, len(primary.Cues)+len(secondary.Cues)),
+	}
+
+	for _, c := range primary.Cues {
+		c.Style = bottom.Name
+		if c.position() == DefaultPosition {
+			c.Position = bottom.Alignment
+		}
+		s.Cues = append(s.Cues, c)
+	}
+	for _, c := range secondary.Cues {
+		c.Style = top.Name
+		if c.position() == DefaultPosition {
+			c.Position = top.Alignment
+		}
+		s.Cues = append(s.Cues, c)
+	}
+
+	sort.SliceStable(s.Cues, func(i, j int) bool { return s.Cues[i].Start < s.Cues[j].Start })
+	return s
+}
+
+// bestOverlap returns the index of the cue overlapping c the most, or -1.
+func bestOverlap(cues []Cue, c Cue) int {
+	best, bestOverlap := -1, time.Duration(0)
+	for i, k := range cues {
+		start, end := k.Start, k.End
+		if c.Start > start {
+			start = c.Start
+		}
+		if c.End < end {
+			end = c.End
+		}
+		if overlap := end - start; overlap > bestOverlap {
+			best, bestOverlap = i, overlap
+		}
+	}
+	return best
+}
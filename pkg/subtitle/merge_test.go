@@ -0,0 +1,43 @@
+package subtitle
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	s := time.Second
+	primary := &Subtitle{Cues: []Cue{
+		{Start: 1 * s, End: 3 * s, Position: 2, Text: "你好"},
+		{Start: 4 * s, End: 6 * s, Position: 8, Text: "标题"},
+	}}
+	secondary := &Subtitle{Cues: []Cue{
+		{Start: 1100 * time.Millisecond, End: 2 * s, Position: 2, Text: "Hello"},
+		{Start: 2500 * time.Millisecond, End: 5500 * time.Millisecond, Position: 2, Text: "Title"},
+		{Start: 7 * s, End: 8 * s, Position: 2, Text: "Alone"},
+	}}
+
+	want := []Cue{
+		{Start: 1 * s, End: 3 * s, Position: 2, Text: "你好\nHello"},
+		{Start: 4 * s, End: 6 * s, Position: 8, Text: "标题\nTitle"},
+		{Start: 7 * s, End: 8 * s, Position: 2, Text: "Alone"},
+	}
+	if got := Merge(primary, secondary); !reflect.DeepEqual(got.Cues, want) {
+		t.Errorf("Merge() = %+v, want %+v", got.Cues, want)
+	}
+
+	top := DefaultStyle
+	top.Name, top.Alignment = "Top", 8
+	got := MergeStyled(primary, secondary, DefaultStyle, top)
+	wantStyled := []Cue{
+		{Start: 1 * s, End: 3 * s, Position: 2, Style: "Default", Text: "你好"},
+		{Start: 1100 * time.Millisecond, End: 2 * s, Position: 8, Style: "Top", Text: "Hello"},
+		{Start: 2500 * time.Millisecond, End: 5500 * time.Millisecond, Position: 8, Style: "Top", Text: "Title"},
+		{Start: 4 * s, End: 6 * s, Position: 8, Style: "Default", Text: "标题"},
+		{Start: 7 * s, End: 8 * s, Position: 8, Style: "Top", Text: "Alone"},
+	}
+	if !reflect.DeepEqual(got.Cues, wantStyled) {
+		t.Errorf("MergeStyled() = %+v, want %+v", got.Cues, wantStyled)
+	}
+}
@@ -0,0 +1,82 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var srtAlignTag = regexp.MustCompile(`^\{\\an([1-9])\}`)
+
+// writeSRT writes s as SubRip. Positions other than the default are kept as
+// a leading {\anN} tag, which most players understand.
+func writeSRT(s *Subtitle) []byte {
+	sub := make([]string, 0, len(s.Cues))
+	for i, c := range s.Cues {
+		content := c.Text
+		if p := c.position(); p != DefaultPosition {
+			content = fmt.Sprintf("{\\an%d}%s", p, content)
+		}
+		sub = append(sub, fmt.Sprintf("%d\n%s --> %s\n%s", i+1, formatTime(c.Start, ","), formatTime(c.End, ","), content))
+	}
+	return []byte(strings.Join(sub, "\n\n") + "\n")
+}
+
+func parseSRT(data []byte) (*Subtitle, error) {
+	s := new(Subtitle)
+	for _, block := range splitBlocks(data) {
+		lines := strings.Split(block, "\n")
+		if _, err := strconv.Atoi(strings.TrimSpace(lines[0])); err == nil && len(lines) > 1 {
+			lines = lines[1:]
+		}
+
+		start, end, _, err := parseTiming(lines[0])
+		if err != nil {
+			return nil, err
+		}
+
+		c := Cue{Start: start, End: end, Position: DefaultPosition, Text: strings.Join(lines[1:], "\n")}
+		if m := srtAlignTag.FindStringSubmatch(c.Text); m != nil {
+			c.Position, _ = strconv.Atoi(m[1])
+			c.Text = c.Text[len(m[0]):]
+		}
+		s.Cues = append(s.Cues, c)
+	}
+	return s, nil
+}
+
+// splitBlocks splits SRT and WebVTT files into blocks separated by blank
+// lines.
+func splitBlocks(data []byte) []string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var blocks []string
+	for _, b := range strings.Split(text, "\n\n") {
+		if b = strings.Trim(b, "\n"); strings.TrimSpace(b) != "" {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// parseTiming parses "start --> end" followed by optional cue settings.
+func parseTiming(line string) (start, end time.Duration, settings string, err error) {
+	from, to, ok := strings.Cut(line, "-->")
+	if !ok {
+		return 0, 0, "", fmt.Errorf("subtitle: invalid timing %q", line)
+	}
+
+	to = strings.TrimSpace(to)
+	if i := strings.IndexAny(to, " \t"); i >= 0 {
+		to, settings = to[:i], strings.TrimSpace(to[i:])
+	}
+
+	if start, err = parseTime(from); err != nil {
+		return 0, 0, "", err
+	}
+	if end, err = parseTime(to); err != nil {
+		return 0, 0, "", err
+	}
+	return start, end, settings, nil
+}
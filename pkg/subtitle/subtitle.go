@@ -0,0 +1,182 @@
+// Package subtitle holds a format-neutral subtitle model with readers and
+// writers for SRT, WebVTT, ASS and the bilibili JSON format.
+package subtitle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a subtitle file format, named after its file extension.
+type Format string
+
+const (
+	SRT  Format = "srt"
+	VTT  Format = "vtt"
+	ASS  Format = "ass"
+	JSON Format = "json"
+	// TXT is a plain transcript. It can be written but not read.
+	TXT Format = "txt"
+)
+
+// Formats lists every format that can be read.
+var Formats = []Format{SRT, VTT, ASS, JSON}
+
+// DefaultPosition is the bottom center position used when a cue does not
+// set one.
+const DefaultPosition = 2
+
+// Cue is a single subtitle event.
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	// Text holds the lines of the cue separated by "\n". Italic, bold and
+	// underline are kept as <i>, <b> and <u> tags.
+	Text string
+	// Position is the numpad alignment of the cue (1 bottom left to 9 top
+	// right). Zero means DefaultPosition.
+	Position int
+	// Style is the name of the ASS style of the cue, empty for the first.
+	Style string
+}
+
+// Style is an ASS style. Sizes and margins are in script pixels of the
+// 1920x1080 canvas written by the ASS encoder.
+type Style struct {
+	Name      string
+	Font      string
+	Size      int
+	Bold      bool
+	Outline   float64
+	Shadow    float64
+	Alignment int
+	MarginL   int
+	MarginR   int
+	MarginV   int
+}
+
+// DefaultStyle is written into ASS files that have no style of their own.
+var DefaultStyle = Style{
+	Name:      "Default",
+	Font:      "Arial",
+	Size:      64,
+	Outline:   3,
+	Shadow:    1,
+	Alignment: DefaultPosition,
+	MarginL:   60,
+	MarginR:   60,
+	MarginV:   50,
+}
+
+// Subtitle is a list of cues and the styles they refer to.
+type Subtitle struct {
+	Styles []Style
+	Cues   []Cue
+}
+
+// ParseFormat returns the Format for a name or file extension such as
+// "srt" or ".srt".
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimPrefix(s, ".")))
+	for _, k := range Formats {
+		if f == k {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("subtitle: unsupported format %q", s)
+}
+
+// Unmarshal decodes data written in format f.
+func Unmarshal(data []byte, f Format) (*Subtitle, error) {
+	data = []byte(strings.TrimPrefix(string(data), "\xef\xbb\xbf"))
+	switch f {
+	case SRT:
+		return parseSRT(data)
+	case VTT:
+		return parseVTT(data)
+	case ASS:
+		return parseASS(data)
+	case JSON:
+		return parseJSON(data)
+	}
+	return nil, fmt.Errorf("subtitle: unsupported format %q", f)
+}
+
+// Marshal encodes s in format f.
+func Marshal(s *Subtitle, f Format) ([]byte, error) {
+	switch f {
+	case SRT:
+		return writeSRT(s), nil
+	case VTT:
+		return writeVTT(s), nil
+	case ASS:
+		return writeASS(s), nil
+	case JSON:
+		return writeJSON(s)
+	case TXT:
+		return writeTXT(s), nil
+	}
+	return nil, fmt.Errorf("subtitle: unsupported format %q", f)
+}
+
+// Convert decodes data from format from and encodes it in format to.
+func Convert(data []byte, from, to Format) ([]byte, error) {
+	s, err := Unmarshal(data, from)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(s, to)
+}
+
+func (c Cue) position() int {
+	if c.Position < 1 || c.Position > 9 {
+		return DefaultPosition
+	}
+	return c.Position
+}
+
+// formatTime formats d as hh:mm:ss followed by sep and milliseconds.
+func formatTime(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := int64(d / time.Millisecond)
+	secs, ms := ms/1000, ms%1000
+	mins, secs := secs/60, secs%60
+	hrs, mins := mins/60, mins%60
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hrs, mins, secs, sep, ms)
+}
+
+// parseTime parses [h:]mm:ss followed by '.' or ',' and a fraction.
+func parseTime(t string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(t), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("subtitle: invalid time %q", t)
+	}
+
+	s, err := strconv.ParseFloat(strings.Replace(parts[len(parts)-1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("subtitle: invalid time %q", t)
+	}
+
+	d, unit := seconds(s), time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, fmt.Errorf("subtitle: invalid time %q", t)
+		}
+		d += time.Duration(n) * unit
+		unit *= 60
+	}
+	return d, nil
+}
+
+// seconds converts a float number of seconds to a Duration.
+func seconds(s float64) time.Duration {
+	if s < 0 {
+		return time.Duration(s*float64(time.Second) - 0.5)
+	}
+	return time.Duration(s*float64(time.Second) + 0.5)
+}
@@ -0,0 +1,186 @@
+package subtitle
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s mismatch:\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		input string
+		to    Format
+		want  string
+	}{
+		{input: "sample.json", to: SRT, want: "sample.srt"},
+		{input: "sample.json", to: VTT, want: "sample.vtt"},
+		{input: "sample.json", to: ASS, want: "sample.ass"},
+		{input: "input.ass", to: SRT, want: "input.srt"},
+		{input: "input.ass", to: ASS, want: "input.out.ass"},
+		{input: "input.ass", to: TXT, want: "input.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			from, err := ParseFormat(filepath.Ext(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Convert(data, from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, tt.want, got)
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"sample.json", "sample.srt", "sample.vtt", "sample.ass", "input.srt", "input.out.ass"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := ParseFormat(filepath.Ext(name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Convert(data, f, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(data) {
+				t.Errorf("round trip of %s:\ngot:\n%s\nwant:\n%s", name, got, data)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONWithoutBody(t *testing.T) {
+	for _, in := range []string{`{}`, `{"args":["dl","1"],"done":[]}`} {
+		if _, err := Unmarshal([]byte(in), JSON); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want error", in)
+		}
+	}
+	if s, err := Unmarshal([]byte(`{"body":[]}`), JSON); err != nil || len(s.Cues) != 0 {
+		t.Errorf("Unmarshal() of an empty body = %v, %v, want no cues", s, err)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{in: "00:00:01,500", want: 1500 * time.Millisecond},
+		{in: "01:01:01.257", want: time.Hour + time.Minute + 1257*time.Millisecond},
+		{in: "0:00:03.25", want: 3250 * time.Millisecond},
+		{in: "02:03.004", want: 2*time.Minute + 3004*time.Millisecond},
+	}
+	for _, tt := range tests {
+		got, err := parseTime(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTime(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestRetimeFile(t *testing.T) {
+	timing := Timing{Shift: 1500 * time.Millisecond, FromFPS: 25, ToFPS: 25}
+	tests := []struct {
+		name string
+		in   string
+		f    Format
+		want string
+	}{
+		{
+			name: "srt",
+			in:   "1\n00:00:01,000 --> 00:00:02,000\nHi\n",
+			f:    SRT,
+			want: "1\n00:00:02,500 --> 00:00:03,500\nHi\n",
+		},
+		{
+			name: "vtt keeps everything but the times",
+			in:   "WEBVTT\n\nSTYLE\n::cue(.loud) { color: red }\n\nNOTE made by hand\n\nintro\n00:01.000 --> 00:00:02.000 position:30% align:start\n<v Bob>Hi</v> <c.loud>there</c>\n",
+			f:    VTT,
+			want: "WEBVTT\n\nSTYLE\n::cue(.loud) { color: red }\n\nNOTE made by hand\n\nintro\n00:00:02.500 --> 00:00:03.500 position:30% align:start\n<v Bob>Hi</v> <c.loud>there</c>\n",
+		},
+		{
+			name: "srt keeps crlf",
+			in:   "1\r\n00:00:01,000 --> 00:00:02,000\r\n<i>Hi</i>\r\n",
+			f:    SRT,
+			want: "1\r\n00:00:02,500 --> 00:00:03,500\r\n<i>Hi</i>\r\n",
+		},
+		{
+			name: "ass keeps tags",
+			in:   "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00,0:00:02.00,Sign,,0,0,0,,{\\pos(10,10)}Hi, there\n",
+			f:    ASS,
+			want: "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:02.50,0:00:03.50,Sign,,0,0,0,,{\\pos(10,10)}Hi, there\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RetimeFile([]byte(tt.in), tt.f, timing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("RetimeFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetimeFileRefused(t *testing.T) {
+	in := []byte(`{"font_size":0.4,"body":[{"from":1,"to":2,"content":"Hi"}]}`)
+	if _, err := RetimeFile(in, JSON, Timing{Shift: time.Second}); err == nil {
+		t.Error("RetimeFile() on JSON succeeded, want error")
+	}
+}
+
+func TestTimingApply(t *testing.T) {
+	tests := []struct {
+		timing Timing
+		in     time.Duration
+		want   time.Duration
+	}{
+		{timing: Timing{Shift: -2 * time.Second}, in: time.Second, want: 0},
+		{timing: Timing{FromFPS: 25, ToFPS: 24}, in: 24 * time.Second, want: 25 * time.Second},
+		{timing: Timing{Shift: time.Second, FromFPS: 24, ToFPS: 25}, in: 25 * time.Second, want: 25 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.timing.Apply(tt.in); got != tt.want {
+			t.Errorf("%+v.Apply(%v) = %v, want %v", tt.timing, tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timing describes how cue times are corrected: first converted from the
+// FromFPS framerate to ToFPS, then moved by Shift.
+type Timing struct {
+	Shift   time.Duration
+	FromFPS float64
+	ToFPS   float64
+}
+
+// IsZero reports whether t leaves every time unchanged.
+func (t Timing) IsZero() bool {
+	return t.Shift == 0 && (t.FromFPS <= 0 || t.ToFPS <= 0 || t.FromFPS == t.ToFPS)
+}
+
+// Apply returns d corrected by t. Times never become negative.
+func (t Timing) Apply(d time.Duration) time.Duration {
+	if t.FromFPS > 0 && t.ToFPS > 0 {
+		d = time.Duration(float64(d)*t.FromFPS/t.ToFPS + 0.5)
+	}
+	if d += t.Shift; d < 0 {
+		return 0
+	}
+	return d
+}
+
+// Retime corrects the start and end of every cue by t.
+func (s *Subtitle) Retime(t Timing) {
+	for i := range s.Cues {
+		s.Cues[i].Start = t.Apply(s.Cues[i].Start)
+		s.Cues[i].End = t.Apply(s.Cues[i].End)
+	}
+}
+
+// RetimeFile corrects the times of a file in format f by t. The file is
+// edited in place, only the cue times change: styles, notes, cue IDs and
+// tags survive. Formats that cannot be edited that way are refused.
+func RetimeFile(data []byte, f Format, t Timing) ([]byte, error) {
+	if t.IsZero() {
+		return data, nil
+	}
+
+	switch f {
+	case ASS:
+		return retimeASS(data, t)
+	case SRT:
+		return retimeCues(data, t, ",")
+	case VTT:
+		return retimeCues(data, t, ".")
+	}
+	return nil, fmt.Errorf("subtitle: cannot correct the times of %s files in place", f)
+}
+
+// retimeCues rewrites the "start --> end" lines of an SRT or WebVTT file,
+// keeping their cue settings, and copies every other line as is.
+func retimeCues(data []byte, t Timing, sep string) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "-->") {
+			continue
+		}
+
+		body := strings.TrimSuffix(line, "\r")
+		start, end, settings, err := parseTiming(body)
+		if err != nil {
+			return nil, err
+		}
+
+		timing := formatTime(t.Apply(start), sep) + " --> " + formatTime(t.Apply(end), sep)
+		if settings != "" {
+			timing += " " + settings
+		}
+		lines[i] = timing + line[len(body):]
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// retimeASS rewrites the start and end of the Dialogue and Comment lines of
+// an ASS script.
+func retimeASS(data []byte, t Timing) ([]byte, error) {
+	var (
+		out     bytes.Buffer
+		section string
+		fields  = assDefaultFields
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			section = strings.ToLower(trimmed)
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		switch k := strings.ToLower(strings.TrimSpace(key)); {
+		case !ok || section != "[events]":
+		case k == "format":
+			fields = splitASSFormat(value)
+		case k == "dialogue" || k == "comment":
+			values := strings.SplitN(strings.TrimLeft(value, " "), ",", len(fields))
+			for i, f := range fields {
+				if i < len(values) && (f == "start" || f == "end") {
+					d, err := parseTime(values[i])
+					if err != nil {
+						return nil, err
+					}
+					values[i] = formatASSTime(t.Apply(d))
+				}
+			}
+			line = key + ": " + strings.Join(values, ",")
+		}
+
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
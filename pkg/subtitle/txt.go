@@ -0,0 +1,19 @@
+package subtitle
+
+import (
+	"regexp"
+	"strings"
+)
+
+var txtTag = regexp.MustCompile(`</?[ibu]>`)
+
+// writeTXT writes the text of every cue as a plain transcript, one line per
+// subtitle line, without timing or formatting.
+func writeTXT(s *Subtitle) []byte {
+	var sb strings.Builder
+	for _, c := range s.Cues {
+		sb.WriteString(txtTag.ReplaceAllString(c.Text, ""))
+		sb.WriteByte('\n')
+	}
+	return []byte(sb.String())
+}
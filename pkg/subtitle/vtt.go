@@ -0,0 +1,135 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	vttEscaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	vttUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&nbsp;", " ", "&lrm;", "\u200e", "&rlm;", "\u200f")
+	// vttTags restores the formatting tags WebVTT shares with SRT.
+	vttTags = strings.NewReplacer(
+		"&lt;i&gt;", "<i>", "&lt;/i&gt;", "</i>",
+		"&lt;b&gt;", "<b>", "&lt;/b&gt;", "</b>",
+		"&lt;u&gt;", "<u>", "&lt;/u&gt;", "</u>",
+	)
+	vttTag = regexp.MustCompile(`</?([a-z]*)[^>]*>`)
+)
+
+// vttSettings maps a numpad position to WebVTT cue settings. The default
+// bottom center placement needs none.
+func vttSettings(position int) string {
+	var settings []string
+	switch position {
+	case 7, 8, 9:
+		settings = append(settings, "line:0")
+	case 4, 5, 6:
+		settings = append(settings, "line:50%,center")
+	}
+
+	switch position {
+	case 1, 4, 7:
+		settings = append(settings, "position:10%", "align:start")
+	case 3, 6, 9:
+		settings = append(settings, "position:90%", "align:end")
+	}
+
+	if len(settings) == 0 {
+		return ""
+	}
+	return " " + strings.Join(settings, " ")
+}
+
+// vttPosition maps WebVTT cue settings back to a numpad position.
+func vttPosition(settings string) int {
+	row, col := 0, 1
+	for _, s := range strings.Fields(settings) {
+		key, value, _ := strings.Cut(s, ":")
+		value, _, _ = strings.Cut(value, ",")
+		switch key {
+		case "line":
+			if p, ok := percent(value); ok {
+				switch {
+				case p < 100.0/3:
+					row = 2
+				case p < 200.0/3:
+					row = 1
+				}
+			} else if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+				row = 2
+			}
+		case "position":
+			if p, ok := percent(value); ok {
+				switch {
+				case p < 100.0/3:
+					col = 0
+				case p > 200.0/3:
+					col = 2
+				}
+			}
+		case "align":
+			switch value {
+			case "start", "left":
+				col = 0
+			case "end", "right":
+				col = 2
+			}
+		}
+	}
+	return row*3 + col + 1
+}
+
+func percent(v string) (float64, bool) {
+	if !strings.HasSuffix(v, "%") {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+	return p, err == nil
+}
+
+func writeVTT(s *Subtitle) []byte {
+	sub := make([]string, 0, len(s.Cues)+1)
+	sub = append(sub, "WEBVTT")
+	for i, c := range s.Cues {
+		sub = append(sub, fmt.Sprintf("%d\n%s --> %s%s\n%s", i+1, formatTime(c.Start, "."), formatTime(c.End, "."), vttSettings(c.position()), vttTags.Replace(vttEscaper.Replace(c.Text))))
+	}
+	return []byte(strings.Join(sub, "\n\n") + "\n")
+}
+
+func parseVTT(data []byte) (*Subtitle, error) {
+	blocks := splitBlocks(data)
+	if len(blocks) == 0 || !strings.HasPrefix(blocks[0], "WEBVTT") {
+		return nil, fmt.Errorf("subtitle: missing WEBVTT header")
+	}
+
+	s := new(Subtitle)
+	for _, block := range blocks[1:] {
+		lines := strings.Split(block, "\n")
+		if !strings.Contains(lines[0], "-->") {
+			// NOTE, STYLE and REGION blocks have no timing, cue
+			// identifiers are followed by one.
+			if len(lines) < 2 || !strings.Contains(lines[1], "-->") {
+				continue
+			}
+			lines = lines[1:]
+		}
+
+		start, end, settings, err := parseTiming(lines[0])
+		if err != nil {
+			return nil, err
+		}
+
+		text := vttTag.ReplaceAllStringFunc(strings.Join(lines[1:], "\n"), func(tag string) string {
+			switch vttTag.FindStringSubmatch(tag)[1] {
+			case "i", "b", "u":
+				return tag
+			}
+			return ""
+		})
+		s.Cues = append(s.Cues, Cue{Start: start, End: end, Position: vttPosition(settings), Text: vttUnescaper.Replace(text)})
+	}
+	return s, nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that allows rate requests per second with
+// bursts of up to burst requests. It is safe for concurrent use and a nil
+// *RateLimiter never waits.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate requests per second. A
+// rate of zero or less disables the limit and returns nil.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a request may be sent or ctx is done, in which case it
+// returns the error of ctx.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	return sleep(ctx, l.reserve(time.Now()))
+}
+
+// reserve takes a token and returns how long the caller has to wait for it
+// to become available. Tokens are handed out in call order, so concurrent
+// callers queue up instead of racing for the next refill.
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
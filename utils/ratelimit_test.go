@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	start := time.Now()
+	l := NewRateLimiter(2, 2)
+	l.last = start
+
+	want := []time.Duration{0, 0, 500 * time.Millisecond, time.Second}
+	for i, w := range want {
+		if got := l.reserve(start); got != w {
+			t.Errorf("reserve() #%d = %v, want %v", i+1, got, w)
+		}
+	}
+
+	// After 2s the queue of two is drained and one token is back.
+	if got := l.reserve(start.Add(2 * time.Second)); got != 0 {
+		t.Errorf("reserve() after refill = %v, want 0", got)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	l := NewRateLimiter(0, 5)
+	if l != nil {
+		t.Fatalf("NewRateLimiter(0, 5) = %v, want nil", l)
+	}
+	if got := l.reserve(time.Now()); got != 0 {
+		t.Errorf("nil reserve() = %v, want 0", got)
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// maxRetryWait caps both the exponential backoff and Retry-After.
+const maxRetryWait = 2 * time.Minute
+
+// HTTPError is returned by Request when the server answers with a status
+// other than 200 OK.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http error %s", e.Status)
+}
+
+// Temporary reports whether the request may succeed if sent again.
+func (e *HTTPError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryable reports whether err is a transient failure: a temporary HTTP
+// status, a timeout, a connection refused or reset, or a connection closed
+// halfway through. Other transport errors, such as an unknown host, an
+// unsupported scheme or a bad certificate, are permanent.
+func isRetryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Temporary()
+	}
+
+	// *url.Error is a net.Error itself, so look at the error it wraps.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryDelay returns how long to wait before retry number attempt+1. The
+// server's Retry-After wins when present, otherwise wait doubles on every
+// attempt with up to 50% random jitter added.
+func retryDelay(wait time.Duration, attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if d > maxRetryWait {
+				return maxRetryWait
+			}
+			return d
+		}
+	}
+
+	if wait <= 0 {
+		return 0
+	}
+
+	d := wait << attempt
+	if d <= 0 || d > maxRetryWait {
+		d = maxRetryWait
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done, in which case it returns the error
+// of ctx.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
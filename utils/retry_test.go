@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRequestRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		wantErr  bool
+		wantHits int
+	}{
+		{
+			name:     "recovers from transient errors",
+			statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusOK},
+			retries:  3,
+			wantHits: 3,
+		},
+		{
+			name:     "gives up after retries",
+			statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			retries:  1,
+			wantErr:  true,
+			wantHits: 2,
+		},
+		{
+			name:     "permanent error is not retried",
+			statuses: []int{http.StatusNotFound, http.StatusOK},
+			retries:  3,
+			wantErr:  true,
+			wantHits: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[hits]
+				hits++
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(status)
+				io.WriteString(w, "ok")
+			}))
+			defer ts.Close()
+
+			body, err := Request(context.Background(), ts.Client(), ts.URL, nil, &RequestOptions{Retries: tt.retries, RetryWait: time.Millisecond})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Request() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				body.Close()
+			}
+			if hits != tt.wantHits {
+				t.Errorf("Request() sent %d requests, want %d", hits, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "temporary status", err: &HTTPError{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "permanent status", err: &HTTPError{StatusCode: http.StatusNotFound}, want: false},
+		{name: "connection refused", err: &url.Error{Op: "Get", URL: "http://localhost", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, want: true},
+		{name: "timeout", err: &url.Error{Op: "Get", URL: "http://example.com", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, want: true},
+		{name: "cut off", err: io.ErrUnexpectedEOF, want: true},
+		{name: "unsupported scheme", err: &url.Error{Op: "Get", URL: "ftp://example.com", Err: errors.New(`unsupported protocol scheme "ftp"`)}, want: false},
+		{name: "no such host", err: &url.Error{Op: "Get", URL: "http://nowhere.invalid", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestPermanentTransportError(t *testing.T) {
+	start := time.Now()
+	_, err := Request(context.Background(), nil, "ftp://example.com/sub.json", nil, &RequestOptions{Retries: 3, RetryWait: 200 * time.Millisecond})
+	if err == nil {
+		t.Fatal("Request() succeeded, want error")
+	}
+	if d := time.Since(start); d >= 200*time.Millisecond {
+		t.Errorf("Request() took %v, want no retry", d)
+	}
+}
+
+func TestRequestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		cancel()
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	start := time.Now()
+	_, err := Request(ctx, ts.Client(), ts.URL, nil, &RequestOptions{Retries: 3})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Request() error = %v, want %v", err, context.Canceled)
+	}
+	if hits != 1 {
+		t.Errorf("Request() sent %d requests, want 1", hits)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("Request() returned after %v, want it to stop waiting", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{value: "", wantOk: false},
+		{value: "120", want: 2 * time.Minute, wantOk: true},
+		{value: "-1", wantOk: false},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, wantOk: true},
+		{value: "soon", wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
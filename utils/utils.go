@@ -1,22 +1,46 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func Request(url string, query map[string]string) (io.ReadCloser, error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// RequestOptions controls how Request sends a request.
+type RequestOptions struct {
+	// Header is set on the request.
+	Header map[string]string
+	// Retries is how many times a request that failed with a retryable
+	// error is sent again.
+	Retries int
+	// RetryWait is the initial delay between retries. It doubles after
+	// every attempt unless the server sends a Retry-After header.
+	RetryWait time.Duration
+	// Limiter paces every attempt, including retries.
+	Limiter *RateLimiter
+}
+
+// Request sends a GET request to url with the given query parameters using
+// client. A nil client falls back to one with a 30s timeout and nil opts
+// sends the request once without extra headers. Canceling ctx aborts the
+// request and any wait before a retry.
+func Request(ctx context.Context, client *http.Client, url string, query map[string]string, opts *RequestOptions) (io.ReadCloser, error) {
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+	if opts == nil {
+		opts = &RequestOptions{}
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -26,17 +50,34 @@ func Request(url string, query map[string]string) (io.ReadCloser, error) {
 		q.Add(j, s)
 	}
 
-	req.URL.RawQuery = q.Encode()
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	for j, s := range opts.Header {
+		req.Header.Set(j, s)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("http error %s", resp.Status)
-	}
+	req.URL.RawQuery = q.Encode()
+	for attempt := 0; ; attempt++ {
+		if err := opts.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			return resp.Body, nil
+		}
 
-	return resp.Body, nil
+		if err == nil {
+			err = &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+			resp.Body.Close()
+		}
+
+		if attempt >= opts.Retries || ctx.Err() != nil || !isRetryable(err) {
+			return nil, err
+		}
+
+		if err := sleep(ctx, retryDelay(opts.RetryWait, attempt, resp)); err != nil {
+			return nil, err
+		}
+	}
 }
 
 func JsonUnmarshal(r io.ReadCloser, t interface{}) error {
@@ -48,13 +89,6 @@ func JsonUnmarshal(r io.ReadCloser, t interface{}) error {
 	return nil
 }
 
-func SecondToTime(tt float64) string {
-	secs, msec := int64(tt), int64(tt*1000)%1000
-	mins, secs := secs/60, secs%60
-	hrs, mins := mins/60, mins%60
-	return fmt.Sprintf("%02d:%02d:%02d,%03d", hrs, mins, secs, msec)
-}
-
 func CleanText(t string) string {
 	toBeReplaces := []string{"\"", "?", "/", ":", "\\", "*", "<", ">", "|"}
 	for _, elem := range toBeReplaces {
@@ -67,22 +101,41 @@ func CleanText(t string) string {
 	return strings.TrimSpace(strings.TrimRight(t, "."))
 }
 
+// WriteFile writes content to a temporary file next to filename and renames
+// it into place, so an interrupted write never leaves a half written file.
+// The file keeps its permissions when it exists and gets mTime as its
+// modification time.
 func WriteFile(filename string, content []byte, mTime time.Time) error {
-	f, err := os.Create(filename)
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	tmp := f.Name()
+	defer os.Remove(tmp)
 
 	if _, err = f.Write(content); err != nil {
+		f.Close()
 		return err
 	}
 
-	if err = os.Chtimes(filename, mTime, mTime); err != nil {
+	if err = f.Close(); err != nil {
 		return err
 	}
 
-	return nil
+	if err = os.Chmod(tmp, mode); err != nil {
+		return err
+	}
+
+	if err = os.Chtimes(tmp, mTime, mTime); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
 }
 
 func ListSelect(list []string, max int) []int {
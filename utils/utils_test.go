@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestListSelect2(t *testing.T) {
@@ -80,3 +83,37 @@ func TestListSelect2(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "sub.srt")
+	if err := os.WriteFile(name, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	mTime := time.Date(2023, 1, 29, 18, 30, 0, 0, time.UTC)
+	if err := WriteFile(name, []byte("new"), mTime); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(name)
+	if err != nil || string(got) != "new" {
+		t.Errorf("content = %q, %v, want %q", got, err, "new")
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mTime) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), mTime)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("files in dir = %v, %v, want only sub.srt", entries, err)
+	}
+}